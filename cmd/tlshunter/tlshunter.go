@@ -1,442 +1,299 @@
+// Command tlshunter scans Android APKs for weaknesses in their TLS/HTTPS
+// configuration and usage.
 package main
 
 import (
 	"bytes"
-	"crypto/x509"
+	"context"
+	"encoding/json"
 	"encoding/xml"
-	"flag"
 	"fmt"
 	"io"
 	"log"
-	"strconv"
+	"net/http"
+	"os"
+	"sort"
 	"strings"
-	"time"
 
 	"github.com/avast/apkparser"
+	"github.com/sigeryang/tlshunter/internal/analyzer"
 	"github.com/sigeryang/tlshunter/internal/manifest"
+	"github.com/sigeryang/tlshunter/internal/proxycas"
+	"github.com/sigeryang/tlshunter/internal/report"
+	"github.com/sigeryang/tlshunter/internal/scanner"
+	"github.com/urfave/cli/v2"
 )
 
-func ParseAPK(file string) (*apkparser.ZipReader, *manifest.Manifest, *apkparser.ResourceTable, error) {
-	zip, err := apkparser.OpenZip(file)
-
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf(`open zip error: %v`, err)
-	}
-
-	buf := new(bytes.Buffer)
-	encoder := xml.NewEncoder(buf)
-	rErr, mErr := apkparser.ParseApkWithZip(zip, encoder)
-
-	if mErr != nil {
-		return nil, nil, nil, fmt.Errorf(`parse manifest error: %v`, mErr)
+func main() {
+	app := &cli.App{
+		Name:  "tlshunter",
+		Usage: "scan Android APKs for TLS/HTTPS configuration weaknesses",
+		Commands: []*cli.Command{
+			scanCommand,
+			serveCommand,
+			rulesCommand,
+			diffCommand,
+		},
 	}
 
-	if rErr != nil {
-		return nil, nil, nil, fmt.Errorf(`parse resources error: %v`, rErr)
+	if err := app.Run(os.Args); err != nil {
+		log.Fatal(err)
 	}
+}
 
-	m := manifest.Manifest{}
-	if err := xml.Unmarshal(buf.Bytes(), &m); err != nil {
-		log.Printf(`unmarshal manifest error: %v`, err)
+// Shared flags across scan/serve/diff/rules so the tool can be wired into
+// CI pipelines that gate on a specific severity or risk.
+var (
+	formatFlag = &cli.StringFlag{
+		Name:  "format",
+		Value: "text",
+		Usage: `output format: "text", "json", or "sarif"`,
 	}
-
-	resourcesFile := zip.File["resources.arsc"]
-	if resourcesFile == nil {
-		return nil, nil, nil, fmt.Errorf("cannot find resources file")
+	severityThresholdFlag = &cli.StringFlag{
+		Name:  "severity-threshold",
+		Value: "note",
+		Usage: `minimum severity to include in the report: "note", "warning", or "error"`,
 	}
-	if err := resourcesFile.Open(); err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to open resources.arsc: %v", err)
+	failOnFlag = &cli.StringFlag{
+		Name:  "fail-on",
+		Usage: `comma-separated RiskType IDs (see the "rules" command) that, if found, cause a non-zero exit`,
 	}
-	defer resourcesFile.Close()
-
-	resources, err := apkparser.ParseResourceTable(resourcesFile)
+)
 
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf(`parse resources error: %v`, rErr)
+func parseSeverity(s string) (report.Level, error) {
+	switch report.Level(s) {
+	case report.LevelNote, report.LevelWarning, report.LevelError:
+		return report.Level(s), nil
+	default:
+		return "", fmt.Errorf(`unknown severity %q: must be "note", "warning", or "error"`, s)
 	}
-
-	return zip, &m, resources, nil
 }
 
-func SDKVersionToAndroidMajor(sdkVersion int) int {
-	switch sdkVersion {
-	case 1, 2, 3, 4:
+func severityRank(l report.Level) int {
+	switch l {
+	case report.LevelNote:
+		return 0
+	case report.LevelWarning:
 		return 1
-	case 5, 6, 7, 8, 9, 10:
+	case report.LevelError:
 		return 2
-	case 11, 12, 13:
-		return 3
-	case 14, 15, 16, 17, 18, 19, 20:
-		return 4
-	case 21, 22:
-		return 5
-	case 23:
-		return 6
-	case 24, 25:
-		return 7
-	case 26, 27:
-		return 8
-	case 28:
-		return 9
-	case 29:
-		return 10
-	case 30:
-		return 11
-	case 31, 32:
-		return 12
-	case 33:
-		return 13
 	default:
-		return 13
+		return 0
 	}
 }
 
-type AndroidDefaults struct {
-	AllowCleartext bool
-	NSCPresence    bool
+// filterBySeverity returns a copy of analyses whose Risks are narrowed down
+// to those at or above threshold. The originals are left untouched so
+// --fail-on can still see every risk regardless of --severity-threshold.
+func filterBySeverity(analyses []*analyzer.Analysis, threshold report.Level) []*analyzer.Analysis {
+	minRank := severityRank(threshold)
+	ret := make([]*analyzer.Analysis, len(analyses))
+	for i, a := range analyses {
+		filtered := *a
+		risks := make([]analyzer.Risk, 0, len(a.Risks))
+		for _, risk := range a.Risks {
+			if severityRank(analyzer.RiskLevel(risk.Type)) >= minRank {
+				risks = append(risks, risk)
+			}
+		}
+		filtered.Risks = risks
+		ret[i] = &filtered
+	}
+	return ret
 }
 
-type Analysis struct {
-	File          string `json:"file"`
-	Name          string `json:"name"`
-	TargetVersion int    `json:"target_version"`
-	Risks         []Risk `json:"risks"`
-
-	defaults AndroidDefaults
-	m        *manifest.Manifest
-	nsc      *manifest.NetworkSecurityConfig
-
-	zip       *apkparser.ZipReader
-	resources *apkparser.ResourceTable
-}
+// matchingRiskIDs returns the set of --fail-on RiskType IDs actually
+// present across analyses, so main can report which ones tripped the gate.
+func matchingRiskIDs(analyses []*analyzer.Analysis, failOn []string) []string {
+	if len(failOn) == 0 {
+		return nil
+	}
+	wanted := make(map[string]bool, len(failOn))
+	for _, id := range failOn {
+		wanted[strings.TrimSpace(id)] = true
+	}
 
-type Risk struct {
-	Type   RiskType `json:"type"`
-	Reason string   `json:"reason"`
+	seen := map[string]bool{}
+	var matched []string
+	for _, a := range analyses {
+		for _, risk := range a.Risks {
+			id := risk.Type.ID()
+			if wanted[id] && !seen[id] {
+				seen[id] = true
+				matched = append(matched, id)
+			}
+		}
+	}
+	sort.Strings(matched)
+	return matched
 }
 
-func (r Risk) String() string {
-	return fmt.Sprintf("Type: %v Reason: %s", r.Type, r.Reason)
+// printAnalysis prints a single APK's analysis in the same form used by
+// "text" format, so scanCommand can stream each one to stdout as it
+// completes instead of waiting to print the whole batch at once.
+func printAnalysis(a *analyzer.Analysis) {
+	fmt.Println(a)
+	fmt.Println()
 }
 
-type RiskType int
-
-//go:generate go run golang.org/x/tools/cmd/stringer -type=RiskType
-const (
-	RiskNSCMissing RiskType = iota
-	RiskCleartext
-	RiskUserAnchors
-	RiskAnchorsOverridePinning
-	RiskUnpinned
-	RiskPinningExpiration
-	RiskProxyAnchors
-	RiskMalformedNSC
-)
-
-func (t RiskType) Description() string {
-	switch t {
-	case RiskNSCMissing:
-		return "Android network security configuration is missing."
-	case RiskCleartext:
-		return "Allow cleartext traffic to be transferred."
-	case RiskUserAnchors:
-		return "Allow users to trust 3rd-party CAs."
-	case RiskAnchorsOverridePinning:
-		return "Trust anchors override pinned certificates."
-	case RiskUnpinned:
-		return "Does not pin any certificates."
-	case RiskProxyAnchors:
-		return "Trust anchors contain proxy tool CA."
-	case RiskMalformedNSC:
-		return "Domains in NSC contain invalid hostnames."
+// printReport writes analyses to stdout in the requested format.
+func printReport(analyses []*analyzer.Analysis, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(analyses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("cannot marshal json report: %w", err)
+		}
+		fmt.Println(string(data))
+	case "sarif":
+		data, err := json.MarshalIndent(analyzer.BuildSARIF(analyses), "", "  ")
+		if err != nil {
+			return fmt.Errorf("cannot marshal sarif report: %w", err)
+		}
+		fmt.Println(string(data))
+	case "text":
+		for _, a := range analyses {
+			printAnalysis(a)
+		}
+		printStatistics(analyses)
 	default:
-		return "(unknown)"
+		return fmt.Errorf(`unknown --format %q: must be "text", "json", or "sarif"`, format)
 	}
+	return nil
 }
 
-func (a *Analysis) check() (ret []Risk) {
-	ret = make([]Risk, 0)
-
-	app := a.m.Application
-	nsc := a.nsc
-	defaults := a.defaults
-
-	if nsc != nil {
-		section := "NSC"
-		{
-			section := fmt.Sprintf("%s base config", section)
-			baseConfig := nsc.BaseConfig
-			if baseConfig == nil {
-				if defaults.AllowCleartext {
-					ret = append(ret, Risk{
-						Type:   RiskCleartext,
-						Reason: fmt.Sprintf("%s defaults permit cleartext traffic.", section),
-					})
-				}
-			} else {
-				if baseConfig.CleartextTrafficPermitted == nil {
-					if defaults.AllowCleartext {
-						ret = append(ret, Risk{
-							Type:   RiskCleartext,
-							Reason: fmt.Sprintf("%s defaults permit cleartext traffic.", section),
-						})
-					}
-				} else if *baseConfig.CleartextTrafficPermitted {
-					ret = append(ret, Risk{
-						Type:   RiskCleartext,
-						Reason: fmt.Sprintf("%s permits cleartext traffic.", section),
-					})
-				}
+var scanCommand = &cli.Command{
+	Name:      "scan",
+	Usage:     "scan one or more APKs and report their TLS risks",
+	ArgsUsage: "<apk> [apk...]",
+	Flags: []cli.Flag{
+		formatFlag,
+		severityThresholdFlag,
+		failOnFlag,
+		&cli.StringFlag{
+			Name:  "extra-proxy-cas",
+			Usage: "path to a newline-delimited list of extra hex SPKI SHA-256 hashes to treat as proxy tool CAs",
+		},
+		&cli.IntFlag{
+			Name:  "concurrency",
+			Usage: "number of APKs to scan in parallel (defaults to the number of CPUs)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			return fmt.Errorf("scan requires at least one APK path")
+		}
 
-				anchors := baseConfig.TrustAnchors
-				if anchors != nil {
-					for i, certs := range anchors.Certificates {
-						section := fmt.Sprintf("%s trust anchors (index: %d)", section, i)
-						if certs.Src == "user" {
-							ret = append(ret, Risk{
-								Type:   RiskUserAnchors,
-								Reason: fmt.Sprintf("%s allow user CAs.", section),
-							})
-						}
-
-						// OverridePins is false by default under base config
-						if certs.OverridePins != nil && *certs.OverridePins {
-							ret = append(ret, Risk{
-								Type:   RiskAnchorsOverridePinning,
-								Reason: fmt.Sprintf("%s override certificate pinning.", section),
-							})
-						}
-
-						if certs.Src != "system" && certs.Src != "user" {
-							res := strings.TrimPrefix(certs.Src, "@")
-							resId, err := strconv.ParseInt(res, 16, 32)
-							if err != nil {
-								continue
-							}
-							entry, err := a.resources.GetResourceEntry(uint32(resId))
-							if err != nil {
-								continue
-							}
-							filename, _ := entry.GetValue().String()
-							if err := a.zip.File[filename].Open(); err != nil {
-								continue
-							}
-							ca, err := io.ReadAll(a.zip.File[filename])
-							if err != nil {
-								continue
-							}
-							cert, err := x509.ParseCertificate(ca)
-							if err != nil {
-								continue
-							}
-							if strings.Contains(strings.ToLower(cert.Subject.String()), "proxy") {
-								ret = append(ret, Risk{
-									Type:   RiskUserAnchors,
-									Reason: fmt.Sprintf(`%s contain proxy tool CA with subject "%s".`, section, cert.Subject.String()),
-								})
-							}
-						}
-					}
-				}
-			}
+		threshold, err := parseSeverity(c.String("severity-threshold"))
+		if err != nil {
+			return err
 		}
-		{
-			section := fmt.Sprintf("%s domain config", section)
-			domainConfig := nsc.DomainConfig
-			var flattenDomainConfig func([]*manifest.DomainConfig) []*manifest.DomainConfig
-			flattenDomainConfig = func(domainConfigs []*manifest.DomainConfig) (ret []*manifest.DomainConfig) {
-				for _, domainConfig := range domainConfigs {
-					if domainConfig != nil {
-						ret = append(ret, domainConfig)
-					}
-					ret = append(ret, flattenDomainConfig(domainConfig.Children)...)
-				}
-				return
+
+		extraProxyCAs := map[string]string{}
+		if path := c.String("extra-proxy-cas"); path != "" {
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf(`cannot open --extra-proxy-cas file "%s": %w`, path, err)
 			}
+			extraProxyCAs, err = proxycas.LoadExtraHashes(f)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf(`cannot parse --extra-proxy-cas file "%s": %w`, path, err)
+			}
+		}
 
-			pinned := false
-			domainConfigs := flattenDomainConfig(domainConfig)
-			for i, domainConfig := range domainConfigs {
-				section := fmt.Sprintf("%s sub config (index: %d)", section, i)
-				if domainConfig.PinSet != nil {
-					expiration, err := time.Parse("2006-01-02", domainConfig.PinSet.Expiration)
-					if err != nil || time.Until(expiration).Hours() <= 10*24 {
-						// (to be) expired within 10 days
-						ret = append(ret, Risk{
-							Type:   RiskPinningExpiration,
-							Reason: fmt.Sprintf("%s pin set (will) hit its expiration.", section),
-						})
-					}
+		scanOne := func(ctx context.Context, file string) (*analyzer.Analysis, *scanner.ScanError) {
+			return analyzeFile(file, extraProxyCAs)
+		}
 
-					if len(domainConfig.PinSet.Pins) > 0 {
-						pinned = true
-					}
-				}
-				for _, domain := range domainConfig.Domains {
-					if strings.HasPrefix(domain.Data, "http") {
-						ret = append(ret, Risk{
-							Type:   RiskMalformedNSC,
-							Reason: fmt.Sprintf(`%s domains contain malformed hostname "%s".`, section, domain.Data),
-						})
-					}
-				}
-				if domainConfig.TrustAnchors != nil {
-					for _, certs := range domainConfig.TrustAnchors.Certificates {
-						if certs.Src != "system" && certs.Src != "user" {
-							res := strings.TrimPrefix(certs.Src, "@")
-							resId, err := strconv.ParseInt(res, 16, 32)
-							if err != nil {
-								continue
-							}
-							entry, err := a.resources.GetResourceEntry(uint32(resId))
-							if err != nil {
-								continue
-							}
-							filename, _ := entry.GetValue().String()
-							if err := a.zip.File[filename].Open(); err != nil {
-								continue
-							}
-							ca, err := io.ReadAll(a.zip.File[filename])
-							if err != nil {
-								continue
-							}
-							cert, err := x509.ParseCertificate(ca)
-							if err != nil {
-								continue
-							}
-							if strings.Contains(strings.ToLower(cert.Subject.String()), "proxy") {
-								ret = append(ret, Risk{
-									Type:   RiskUserAnchors,
-									Reason: fmt.Sprintf(`%s trust anchors contain proxy tool CA with subject "%s".`, section, cert.Subject.String()),
-								})
-							}
-						}
-					}
-				}
+		format := c.String("format")
+		analyses, scanErrs := scanner.Run(c.Context, c.Args().Slice(), c.Int("concurrency"), scanOne, func(file string, analysis *analyzer.Analysis, scanErr *scanner.ScanError) {
+			if scanErr != nil {
+				log.Printf("cannot scan %v", scanErr)
+				return
 			}
-
-			if !pinned {
-				ret = append(ret, Risk{
-					Type:   RiskUnpinned,
-					Reason: fmt.Sprintf("%s does not contain pinned certificates.", section),
-				})
+			// "text" output has no batch structure (unlike a json array or a
+			// single sarif log), so print each analysis as soon as it's ready
+			// instead of waiting for the whole scan to finish - the whole
+			// point of streaming results through onResult.
+			if format == "text" {
+				filtered := filterBySeverity([]*analyzer.Analysis{analysis}, threshold)
+				printAnalysis(filtered[0])
 			}
+		})
+		if len(scanErrs) > 0 {
+			log.Printf("%d of %d files failed to scan", len(scanErrs), c.NArg())
 		}
-	} else {
-		section := "Manifest"
-		if defaults.NSCPresence {
-			ret = append(ret, Risk{
-				Type:   RiskNSCMissing,
-				Reason: fmt.Sprintf("%s does not specify NSC where target Android version supports it.", section),
-			})
-		}
-
-		if app.UsesCleartextTraffic == nil {
-			if defaults.AllowCleartext {
-				ret = append(ret, Risk{
-					Type:   RiskCleartext,
-					Reason: fmt.Sprintf("%s defaults permit cleartext traffic.", section),
-				})
-			}
-		} else if *app.UsesCleartextTraffic {
-			ret = append(ret, Risk{
-				Type:   RiskCleartext,
-				Reason: fmt.Sprintf("%s permits cleartext traffic.", section),
-			})
+
+		filteredAnalyses := filterBySeverity(analyses, threshold)
+		if format == "text" {
+			printStatistics(filteredAnalyses)
+		} else if err := printReport(filteredAnalyses, format); err != nil {
+			return err
 		}
-	}
 
-	return
-}
+		if failOn := c.String("fail-on"); failOn != "" {
+			if matched := matchingRiskIDs(analyses, strings.Split(failOn, ",")); len(matched) > 0 {
+				return cli.Exit(fmt.Sprintf("--fail-on matched: %s", strings.Join(matched, ", ")), 1)
+			}
+		}
 
-func (a *Analysis) String() string {
-	risks := []string{}
-	for i, risk := range a.Risks {
-		risks = append(risks, fmt.Sprintf("    %d. %s", i+1, risk.String()))
-	}
-	return strings.TrimPrefix(fmt.Sprintf(`
-File    : %s
-Name    : %s
-Version : Android %d (target)
-Risks   :
-%s`, a.File, a.Name, a.TargetVersion, strings.Join(risks, "\n")), "\n")
+		return nil
+	},
 }
 
-func Analyze(file string, m *manifest.Manifest, nsc *manifest.NetworkSecurityConfig, zip *apkparser.ZipReader, resources *apkparser.ResourceTable) (*Analysis, error) {
-	ret := &Analysis{
-		File:          file,
-		Name:          m.Application.Name,
-		TargetVersion: SDKVersionToAndroidMajor(m.UsesSDK.TargetSDKVersion),
-		m:             m,
-		nsc:           nsc,
-		zip:           zip,
-		resources:     resources,
+// analyzeFile runs the full ParseAPK -> NSC parse -> Analyze pipeline
+// against a single APK, the shared core of both the scan and serve
+// commands.
+func analyzeFile(file string, extraProxyCAs map[string]string) (*analyzer.Analysis, *scanner.ScanError) {
+	zip, m, resources, sigInfo, scanErr := analyzer.ParseAPK(file)
+	if scanErr != nil {
+		return nil, scanErr
 	}
-
-	// Android 7+ supports NSC
-	// Android 9+ disables cleartext traffic by default
-
-	ret.defaults = AndroidDefaults{
-		AllowCleartext: ret.TargetVersion < 9,
-		NSCPresence:    ret.TargetVersion >= 7,
+	defer zip.Close()
+
+	nsc := (*manifest.NetworkSecurityConfig)(nil)
+	var nscData []byte
+	nscFile := m.Application.NetworkSecurityConfig
+	if nscFile != "" {
+		zipFile, ok := zip.File[nscFile]
+		if !ok {
+			return nil, scanner.NewError(file, scanner.StageParseNSC, fmt.Errorf(`cannot find nsc file "%s" in APK`, nscFile))
+		}
+		if err := zipFile.Open(); err != nil {
+			return nil, scanner.NewError(file, scanner.StageParseNSC, err)
+		}
+		buffer := new(bytes.Buffer)
+		encoder := xml.NewEncoder(buffer)
+		currentNSC := manifest.NetworkSecurityConfig{}
+		if err := apkparser.ParseXml(zipFile, encoder, nil); err != nil {
+			return nil, scanner.NewError(file, scanner.StageParseNSC, err)
+		} else if err := xml.Unmarshal(buffer.Bytes(), &currentNSC); err != nil {
+			return nil, scanner.NewError(file, scanner.StageParseNSC, err)
+		}
+		nsc = &currentNSC
+		nscData = buffer.Bytes()
 	}
 
-	ret.Risks = ret.check()
-
-	return ret, nil
+	analysis, err := analyzer.Analyze(file, m, nsc, nscFile, nscData, extraProxyCAs, sigInfo, zip, resources)
+	if err != nil {
+		return nil, scanner.NewError(file, scanner.StageAnalyze, err)
+	}
+	return analysis, nil
 }
 
-func main() {
-	flag.Parse()
-
-	riskMap := make(map[RiskType]map[string][]*Analysis)
-
-	for _, file := range flag.Args() {
-		zip, m, resources, err := ParseAPK(file)
-		if err != nil {
-			log.Printf(`cannot parse APK "%s": %v`, file, err)
-			continue
-		}
-		defer zip.Close()
-
-		nsc := (*manifest.NetworkSecurityConfig)(nil)
-		if m.Application.NetworkSecurityConfig != "" {
-			if zipFile, ok := zip.File[m.Application.NetworkSecurityConfig]; ok {
-				if err := zipFile.Open(); err != nil {
-					log.Printf(`cannot read nsc of "%s": %v`, file, err)
-				} else {
-					buffer := new(bytes.Buffer)
-					encoder := xml.NewEncoder(buffer)
-					currentNSC := manifest.NetworkSecurityConfig{}
-					if err := apkparser.ParseXml(zipFile, encoder, nil); err != nil {
-						log.Printf(`cannot parse nsc of "%s": %v`, file, err)
-					} else if err := xml.Unmarshal(buffer.Bytes(), &currentNSC); err != nil {
-						log.Printf(`cannot parse nsc of "%s": %v`, file, err)
-					} else {
-						nsc = &currentNSC
-					}
-				}
-			} else {
-				log.Printf(`cannot find nsc of "%s": %v`, file, err)
-			}
-		}
-
-		analysis, err := Analyze(file, m, nsc, zip, resources)
-		if err != nil {
-			log.Printf(`cannot analyze "%s": %v`, file, err)
-			continue
-		}
-
+// printStatistics prints the aggregated per-risk-type, per-reason summary
+// that follows the per-APK listing in text mode.
+func printStatistics(analyses []*analyzer.Analysis) {
+	riskMap := make(map[analyzer.RiskType]map[string][]*analyzer.Analysis)
+	for _, analysis := range analyses {
 		for _, risk := range analysis.Risks {
 			if riskMap[risk.Type] == nil {
-				riskMap[risk.Type] = make(map[string][]*Analysis)
+				riskMap[risk.Type] = make(map[string][]*analyzer.Analysis)
 			}
 			reasonMap := riskMap[risk.Type]
 			reasonMap[risk.Reason] = append(reasonMap[risk.Reason], analysis)
 		}
-
-		fmt.Println(analysis)
-		fmt.Println()
 	}
 
 	fmt.Println("Statistics:")
@@ -459,3 +316,321 @@ func main() {
 		fmt.Println()
 	}
 }
+
+var serveCommand = &cli.Command{
+	Name:  "serve",
+	Usage: "run an HTTP server that accepts APK uploads and returns a report",
+	Flags: []cli.Flag{
+		formatFlag,
+		severityThresholdFlag,
+		&cli.StringFlag{
+			Name:  "addr",
+			Value: ":8080",
+			Usage: "address to listen on",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		threshold, err := parseSeverity(c.String("severity-threshold"))
+		if err != nil {
+			return err
+		}
+		defaultFormat := c.String("format")
+		// The HTTP endpoint renders only json or sarif, so the shared
+		// flag's "text" default falls back to json here.
+		if defaultFormat == "text" {
+			defaultFormat = "json"
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/scan", func(w http.ResponseWriter, r *http.Request) {
+			handleScan(w, r, defaultFormat, threshold)
+		})
+
+		addr := c.String("addr")
+		log.Printf("listening on %s", addr)
+		return http.ListenAndServe(addr, mux)
+	},
+}
+
+// handleScan analyzes a single APK uploaded as the multipart field "apk" and
+// writes the report in the format requested by the "format" query parameter
+// (falling back to defaultFormat).
+func handleScan(w http.ResponseWriter, r *http.Request, defaultFormat string, threshold report.Level) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "expected POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file, _, err := r.FormFile("apk")
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`missing "apk" upload: %v`, err), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	tmp, err := os.CreateTemp("", "tlshunter-upload-*.apk")
+	if err != nil {
+		http.Error(w, "cannot buffer upload", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, file); err != nil {
+		http.Error(w, fmt.Sprintf("cannot buffer upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	analysis, scanErr := analyzeFile(tmp.Name(), nil)
+	if scanErr != nil {
+		http.Error(w, scanErr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = defaultFormat
+	}
+	analyses := filterBySeverity([]*analyzer.Analysis{analysis}, threshold)
+
+	var body interface{}
+	switch format {
+	case "sarif":
+		body = analyzer.BuildSARIF(analyses)
+	case "json", "":
+		body = analyses
+	default:
+		http.Error(w, fmt.Sprintf(`unknown format %q: must be "json" or "sarif"`, format), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("cannot encode response: %v", err)
+	}
+}
+
+var rulesCommand = &cli.Command{
+	Name:  "rules",
+	Usage: "list every RiskType this tool can report, with its description and severity",
+	Flags: []cli.Flag{formatFlag},
+	Action: func(c *cli.Context) error {
+		type rule struct {
+			ID          string       `json:"id"`
+			Severity    report.Level `json:"severity"`
+			Description string       `json:"description"`
+		}
+		rules := make([]rule, 0, len(analyzer.AllRiskTypes))
+		for _, t := range analyzer.AllRiskTypes {
+			rules = append(rules, rule{ID: t.ID(), Severity: analyzer.RiskLevel(t), Description: t.Description()})
+		}
+
+		switch c.String("format") {
+		case "json", "sarif":
+			data, err := json.MarshalIndent(rules, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		default:
+			for _, r := range rules {
+				fmt.Printf("%-28s [%-7s] %s\n", r.ID, r.Severity, r.Description)
+			}
+		}
+		return nil
+	},
+}
+
+// diffEntry is one app's risk delta between the old and new report, the
+// unit both printDiff and --fail-on operate on.
+type diffEntry struct {
+	Name       string   `json:"name"`
+	New        bool     `json:"new"`
+	Introduced []string `json:"introduced,omitempty"`
+	Resolved   []string `json:"resolved,omitempty"`
+}
+
+var diffCommand = &cli.Command{
+	Name:      "diff",
+	Usage:     "compare two `scan --format json` reports and show risks introduced or resolved between app versions",
+	ArgsUsage: "<old.json> <new.json>",
+	Flags: []cli.Flag{
+		formatFlag,
+		severityThresholdFlag,
+		failOnFlag,
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 2 {
+			return fmt.Errorf("diff requires exactly two report files")
+		}
+
+		threshold, err := parseSeverity(c.String("severity-threshold"))
+		if err != nil {
+			return err
+		}
+
+		oldAnalyses, err := loadAnalysesJSON(c.Args().Get(0))
+		if err != nil {
+			return err
+		}
+		newAnalyses, err := loadAnalysesJSON(c.Args().Get(1))
+		if err != nil {
+			return err
+		}
+		oldAnalyses = filterBySeverity(oldAnalyses, threshold)
+		newAnalyses = filterBySeverity(newAnalyses, threshold)
+
+		oldByName := make(map[string]*analyzer.Analysis, len(oldAnalyses))
+		for _, a := range oldAnalyses {
+			oldByName[a.Name] = a
+		}
+		newByName := make(map[string]*analyzer.Analysis, len(newAnalyses))
+		for _, a := range newAnalyses {
+			newByName[a.Name] = a
+		}
+
+		names := make([]string, 0, len(newByName))
+		for name := range newByName {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var entries []diffEntry
+		var introducedRisks []*analyzer.Analysis
+		for _, name := range names {
+			old, existed := oldByName[name]
+			current := newByName[name]
+
+			oldRisks := riskSet(old)
+			newRisks := riskSet(current)
+
+			var introduced, resolved []string
+			for id := range newRisks {
+				if !oldRisks[id] {
+					introduced = append(introduced, id)
+				}
+			}
+			for id := range oldRisks {
+				if !newRisks[id] {
+					resolved = append(resolved, id)
+				}
+			}
+			sort.Strings(introduced)
+			sort.Strings(resolved)
+
+			if existed && len(introduced) == 0 && len(resolved) == 0 {
+				continue
+			}
+			entries = append(entries, diffEntry{Name: name, New: !existed, Introduced: introduced, Resolved: resolved})
+			if len(introduced) > 0 {
+				introducedRisks = append(introducedRisks, onlyRisks(current, introduced))
+			}
+		}
+
+		if err := printDiff(entries, introducedRisks, c.String("format")); err != nil {
+			return err
+		}
+
+		if failOn := c.String("fail-on"); failOn != "" {
+			wanted := make(map[string]bool)
+			for _, id := range strings.Split(failOn, ",") {
+				wanted[strings.TrimSpace(id)] = true
+			}
+			seen := map[string]bool{}
+			var matched []string
+			for _, e := range entries {
+				for _, id := range e.Introduced {
+					if wanted[id] && !seen[id] {
+						seen[id] = true
+						matched = append(matched, id)
+					}
+				}
+			}
+			sort.Strings(matched)
+			if len(matched) > 0 {
+				return cli.Exit(fmt.Sprintf("--fail-on matched introduced risk(s): %s", strings.Join(matched, ", ")), 1)
+			}
+		}
+
+		return nil
+	},
+}
+
+// onlyRisks returns a copy of a narrowed down to the risks whose RiskType ID
+// is in ids, for the sarif diff output (a SARIF result only makes sense for
+// risks actually present in the new scan).
+func onlyRisks(a *analyzer.Analysis, ids []string) *analyzer.Analysis {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	filtered := *a
+	risks := make([]analyzer.Risk, 0, len(ids))
+	for _, risk := range a.Risks {
+		if wanted[risk.Type.ID()] {
+			risks = append(risks, risk)
+		}
+	}
+	filtered.Risks = risks
+	return &filtered
+}
+
+// printDiff writes the per-app diff entries in the requested format. The
+// sarif format reports only the introduced risks, across apps that gained
+// any, so the result stream lines up with what broke CI.
+func printDiff(entries []diffEntry, introducedRisks []*analyzer.Analysis, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("cannot marshal json report: %w", err)
+		}
+		fmt.Println(string(data))
+	case "sarif":
+		data, err := json.MarshalIndent(analyzer.BuildSARIF(introducedRisks), "", "  ")
+		if err != nil {
+			return fmt.Errorf("cannot marshal sarif report: %w", err)
+		}
+		fmt.Println(string(data))
+	case "text":
+		for _, e := range entries {
+			if e.New {
+				fmt.Printf("%s: new app, %d risk(s)\n", e.Name, len(e.Introduced))
+				continue
+			}
+			fmt.Printf("%s:\n", e.Name)
+			if len(e.Introduced) > 0 {
+				fmt.Printf("    + introduced: %s\n", strings.Join(e.Introduced, ", "))
+			}
+			if len(e.Resolved) > 0 {
+				fmt.Printf("    - resolved:   %s\n", strings.Join(e.Resolved, ", "))
+			}
+		}
+	default:
+		return fmt.Errorf(`unknown --format %q: must be "text", "json", or "sarif"`, format)
+	}
+	return nil
+}
+
+func riskSet(a *analyzer.Analysis) map[string]bool {
+	if a == nil {
+		return nil
+	}
+	set := make(map[string]bool, len(a.Risks))
+	for _, risk := range a.Risks {
+		set[risk.Type.ID()] = true
+	}
+	return set
+}
+
+func loadAnalysesJSON(path string) ([]*analyzer.Analysis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", path, err)
+	}
+	var analyses []*analyzer.Analysis
+	if err := json.Unmarshal(data, &analyses); err != nil {
+		return nil, fmt.Errorf("cannot parse %q as a scan report: %w", path, err)
+	}
+	return analyses, nil
+}
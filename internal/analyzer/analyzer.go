@@ -0,0 +1,715 @@
+// Package analyzer is the reusable library core of tlshunter: parsing an
+// APK and checking its manifest, Network Security Configuration, and DEX
+// bytecode for TLS-related risks. It has no dependency on any particular
+// CLI or output format, so downstream Go code can import it directly.
+package analyzer
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avast/apkparser"
+	"github.com/sigeryang/tlshunter/internal/apksig"
+	"github.com/sigeryang/tlshunter/internal/dex"
+	"github.com/sigeryang/tlshunter/internal/manifest"
+	"github.com/sigeryang/tlshunter/internal/proxycas"
+	"github.com/sigeryang/tlshunter/internal/scanner"
+)
+
+func ParseAPK(file string) (*apkparser.ZipReader, *manifest.Manifest, *apkparser.ResourceTable, *apksig.Info, *scanner.ScanError) {
+	zip, err := apkparser.OpenZip(file)
+	if err != nil {
+		return nil, nil, nil, nil, scanner.NewError(file, scanner.StageOpenZip, err)
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := xml.NewEncoder(buf)
+	rErr, mErr := apkparser.ParseApkWithZip(zip, encoder)
+
+	if mErr != nil {
+		return nil, nil, nil, nil, scanner.NewError(file, scanner.StageParseManifest, mErr)
+	}
+
+	if rErr != nil {
+		return nil, nil, nil, nil, scanner.NewError(file, scanner.StageParseResources, rErr)
+	}
+
+	m := manifest.Manifest{}
+	if err := xml.Unmarshal(buf.Bytes(), &m); err != nil {
+		log.Printf(`unmarshal manifest error: %v`, err)
+	}
+
+	resourcesFile := zip.File["resources.arsc"]
+	if resourcesFile == nil {
+		return nil, nil, nil, nil, scanner.NewError(file, scanner.StageParseResources, fmt.Errorf("cannot find resources file"))
+	}
+	if err := resourcesFile.Open(); err != nil {
+		return nil, nil, nil, nil, scanner.NewError(file, scanner.StageParseResources, fmt.Errorf("failed to open resources.arsc: %w", err))
+	}
+	defer resourcesFile.Close()
+
+	resources, err := apkparser.ParseResourceTable(resourcesFile)
+	if err != nil {
+		return nil, nil, nil, nil, scanner.NewError(file, scanner.StageParseResources, err)
+	}
+
+	names := make([]string, 0, len(zip.File))
+	for name := range zip.File {
+		names = append(names, name)
+	}
+	sigInfo, err := apksig.Inspect(file, names)
+	if err != nil {
+		log.Printf(`apk signature inspection error for "%s": %v`, file, err)
+		sigInfo = &apksig.Info{}
+	}
+
+	return zip, &m, resources, sigInfo, nil
+}
+
+func SDKVersionToAndroidMajor(sdkVersion int) int {
+	switch sdkVersion {
+	case 1, 2, 3, 4:
+		return 1
+	case 5, 6, 7, 8, 9, 10:
+		return 2
+	case 11, 12, 13:
+		return 3
+	case 14, 15, 16, 17, 18, 19, 20:
+		return 4
+	case 21, 22:
+		return 5
+	case 23:
+		return 6
+	case 24, 25:
+		return 7
+	case 26, 27:
+		return 8
+	case 28:
+		return 9
+	case 29:
+		return 10
+	case 30:
+		return 11
+	case 31, 32:
+		return 12
+	case 33:
+		return 13
+	default:
+		return 13
+	}
+}
+
+type AndroidDefaults struct {
+	AllowCleartext bool
+	NSCPresence    bool
+}
+
+type Analysis struct {
+	File          string `json:"file"`
+	Name          string `json:"name"`
+	TargetVersion int    `json:"target_version"`
+	Risks         []Risk `json:"risks"`
+
+	defaults      AndroidDefaults
+	m             *manifest.Manifest
+	nsc           *manifest.NetworkSecurityConfig
+	nscFile       string
+	nscData       []byte
+	extraProxyCAs map[string]string
+	sigInfo       *apksig.Info
+
+	zip       *apkparser.ZipReader
+	resources *apkparser.ResourceTable
+}
+
+type Risk struct {
+	Type   RiskType `json:"type"`
+	Reason string   `json:"reason"`
+
+	// InNSCFile is true if this risk was found in the app's Network
+	// Security Config file, so report formats that can point at a location
+	// inside the APK (e.g. SARIF) know to target nscFile instead of the
+	// manifest or APK root.
+	InNSCFile bool `json:"inNSCFile,omitempty"`
+	// NSCLine is the 1-indexed line, within the NSC file, of the specific
+	// <domain-config> block this risk was raised against. Zero if the risk
+	// isn't tied to one (e.g. it came from base-config or debug-overrides).
+	NSCLine int `json:"nscLine,omitempty"`
+}
+
+func (r Risk) String() string {
+	return fmt.Sprintf("Type: %v Reason: %s", r.Type, r.Reason)
+}
+
+type RiskType int
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=RiskType
+const (
+	RiskNSCMissing RiskType = iota
+	RiskCleartext
+	RiskUserAnchors
+	RiskAnchorsOverridePinning
+	RiskUnpinned
+	RiskPinningExpiration
+	RiskProxyAnchors
+	RiskMalformedNSC
+	RiskTrustManagerBypass
+	RiskHostnameVerifierBypass
+	RiskWebViewSSLBypass
+	RiskMissingCertificatePinner
+	RiskDebugOverridesInRelease
+	RiskSignedV1Only
+	RiskDebugSigningCert
+	RiskRotatedKeyMismatch
+)
+
+// AllRiskTypes lists every RiskType in declaration order, used to build the
+// rule catalog for report formats such as SARIF and the `rules` CLI
+// subcommand.
+var AllRiskTypes = []RiskType{
+	RiskNSCMissing,
+	RiskCleartext,
+	RiskUserAnchors,
+	RiskAnchorsOverridePinning,
+	RiskUnpinned,
+	RiskPinningExpiration,
+	RiskProxyAnchors,
+	RiskMalformedNSC,
+	RiskTrustManagerBypass,
+	RiskHostnameVerifierBypass,
+	RiskWebViewSSLBypass,
+	RiskMissingCertificatePinner,
+	RiskDebugOverridesInRelease,
+	RiskSignedV1Only,
+	RiskDebugSigningCert,
+	RiskRotatedKeyMismatch,
+}
+
+// ID returns the stable, machine-readable identifier used for this
+// RiskType outside of Go, e.g. as a SARIF ruleId or a --fail-on value.
+func (t RiskType) ID() string {
+	switch t {
+	case RiskNSCMissing:
+		return "NSCMissing"
+	case RiskCleartext:
+		return "Cleartext"
+	case RiskUserAnchors:
+		return "UserAnchors"
+	case RiskAnchorsOverridePinning:
+		return "AnchorsOverridePinning"
+	case RiskUnpinned:
+		return "Unpinned"
+	case RiskPinningExpiration:
+		return "PinningExpiration"
+	case RiskProxyAnchors:
+		return "ProxyAnchors"
+	case RiskMalformedNSC:
+		return "MalformedNSC"
+	case RiskTrustManagerBypass:
+		return "TrustManagerBypass"
+	case RiskHostnameVerifierBypass:
+		return "HostnameVerifierBypass"
+	case RiskWebViewSSLBypass:
+		return "WebViewSSLBypass"
+	case RiskMissingCertificatePinner:
+		return "MissingCertificatePinner"
+	case RiskDebugOverridesInRelease:
+		return "DebugOverridesInRelease"
+	case RiskSignedV1Only:
+		return "SignedV1Only"
+	case RiskDebugSigningCert:
+		return "DebugSigningCert"
+	case RiskRotatedKeyMismatch:
+		return "RotatedKeyMismatch"
+	default:
+		return "Unknown"
+	}
+}
+
+func (t RiskType) Description() string {
+	switch t {
+	case RiskNSCMissing:
+		return "Android network security configuration is missing."
+	case RiskCleartext:
+		return "Allow cleartext traffic to be transferred."
+	case RiskUserAnchors:
+		return "Allow users to trust 3rd-party CAs."
+	case RiskAnchorsOverridePinning:
+		return "Trust anchors override pinned certificates."
+	case RiskUnpinned:
+		return "Does not pin any certificates."
+	case RiskPinningExpiration:
+		return "Pin set has expired, expires within 10 days, or has no valid expiration date."
+	case RiskProxyAnchors:
+		return "Trust anchors contain proxy tool CA."
+	case RiskMalformedNSC:
+		return "Domains in NSC contain invalid hostnames."
+	case RiskTrustManagerBypass:
+		return "X509TrustManager implementation trusts any certificate."
+	case RiskHostnameVerifierBypass:
+		return "HostnameVerifier implementation accepts any hostname."
+	case RiskWebViewSSLBypass:
+		return "WebViewClient ignores TLS errors by calling SslErrorHandler.proceed."
+	case RiskMissingCertificatePinner:
+		return "OkHttp is used without certificate pinning."
+	case RiskDebugOverridesInRelease:
+		return "Debug-overrides trust anchors are active on a debuggable build that doesn't look like a debug build."
+	case RiskSignedV1Only:
+		return "Signed with only the v1 (JAR) scheme despite targeting an SDK version that requires v2+."
+	case RiskDebugSigningCert:
+		return "Signing certificate is the Android debug key, or has expired."
+	case RiskRotatedKeyMismatch:
+		return "v3 signing lineage does not chain back to the v2 signer."
+	default:
+		return "(unknown)"
+	}
+}
+
+// resolveAnchorCert resolves a trust-anchor certificate's "@hex" resource
+// reference to the DER-encoded entry inside the APK and parses it.
+func (a *Analysis) resolveAnchorCert(src string) (*x509.Certificate, error) {
+	res := strings.TrimPrefix(src, "@")
+	resId, err := strconv.ParseInt(res, 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := a.resources.GetResourceEntry(uint32(resId))
+	if err != nil {
+		return nil, err
+	}
+	filename, _ := entry.GetValue().String()
+	if err := a.zip.File[filename].Open(); err != nil {
+		return nil, err
+	}
+	ca, err := io.ReadAll(a.zip.File[filename])
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(ca)
+}
+
+// resolvedDomainConfig is a <domain-config> with cleartextTrafficPermitted,
+// trust-anchors, and pin-set resolved against its ancestors, per the NSC
+// inheritance rules: an attribute a child omits is inherited from its
+// parent domain-config, and ultimately from base-config.
+//
+// Reference: https://developer.android.com/training/articles/security-config#FileFormat
+type resolvedDomainConfig struct {
+	cleartextTrafficPermitted *bool
+	trustAnchors              *manifest.TrustAnchors
+	pinSet                    *manifest.PinSet
+	domains                   []*manifest.Domain
+}
+
+// flattenDomainConfig resolves a <domain-config> tree into a flat list,
+// each entry carrying its own domains plus whichever attributes it
+// inherited from parent. A nil entry (which can occur in a malformed NSC)
+// is skipped rather than dereferenced.
+func flattenDomainConfig(domainConfigs []*manifest.DomainConfig, parent resolvedDomainConfig) []resolvedDomainConfig {
+	var ret []resolvedDomainConfig
+	for _, domainConfig := range domainConfigs {
+		if domainConfig == nil {
+			continue
+		}
+
+		resolved := resolvedDomainConfig{
+			cleartextTrafficPermitted: domainConfig.CleartextTrafficPermitted,
+			trustAnchors:              domainConfig.TrustAnchors,
+			pinSet:                    domainConfig.PinSet,
+			domains:                   domainConfig.Domains,
+		}
+		if resolved.cleartextTrafficPermitted == nil {
+			resolved.cleartextTrafficPermitted = parent.cleartextTrafficPermitted
+		}
+		if resolved.trustAnchors == nil {
+			resolved.trustAnchors = parent.trustAnchors
+		}
+		if resolved.pinSet == nil {
+			resolved.pinSet = parent.pinSet
+		}
+
+		ret = append(ret, resolved)
+		ret = append(ret, flattenDomainConfig(domainConfig.Children, resolved)...)
+	}
+	return ret
+}
+
+// looksLikeDebugBuild is a best-effort heuristic for whether an APK is a
+// debug build rather than a release build mistakenly shipped with
+// android:debuggable="true": debug builds conventionally carry "debug" in
+// their application class name or label (e.g. the default Android Gradle
+// plugin debug build type suffixes the application ID with ".debug").
+func looksLikeDebugBuild(app manifest.Application) bool {
+	return strings.Contains(strings.ToLower(app.Name), "debug") || strings.Contains(strings.ToLower(app.Label), "debug")
+}
+
+func (a *Analysis) check() (ret []Risk) {
+	ret = make([]Risk, 0)
+
+	app := a.m.Application
+	nsc := a.nsc
+	defaults := a.defaults
+
+	if nsc != nil {
+		section := "NSC"
+		{
+			section := fmt.Sprintf("%s base config", section)
+			baseConfig := nsc.BaseConfig
+			if baseConfig == nil {
+				if defaults.AllowCleartext {
+					ret = append(ret, Risk{
+						Type:      RiskCleartext,
+						Reason:    fmt.Sprintf("%s defaults permit cleartext traffic.", section),
+						InNSCFile: true,
+					})
+				}
+			} else {
+				if baseConfig.CleartextTrafficPermitted == nil {
+					if defaults.AllowCleartext {
+						ret = append(ret, Risk{
+							Type:      RiskCleartext,
+							Reason:    fmt.Sprintf("%s defaults permit cleartext traffic.", section),
+							InNSCFile: true,
+						})
+					}
+				} else if *baseConfig.CleartextTrafficPermitted {
+					ret = append(ret, Risk{
+						Type:      RiskCleartext,
+						Reason:    fmt.Sprintf("%s permits cleartext traffic.", section),
+						InNSCFile: true,
+					})
+				}
+
+				anchors := baseConfig.TrustAnchors
+				if anchors != nil {
+					for i, certs := range anchors.Certificates {
+						section := fmt.Sprintf("%s trust anchors (index: %d)", section, i)
+						if certs.Src == "user" {
+							ret = append(ret, Risk{
+								Type:      RiskUserAnchors,
+								Reason:    fmt.Sprintf("%s allow user CAs.", section),
+								InNSCFile: true,
+							})
+						}
+
+						// OverridePins is false by default under base config
+						if certs.OverridePins != nil && *certs.OverridePins {
+							ret = append(ret, Risk{
+								Type:      RiskAnchorsOverridePinning,
+								Reason:    fmt.Sprintf("%s override certificate pinning.", section),
+								InNSCFile: true,
+							})
+						}
+
+						if certs.Src != "system" && certs.Src != "user" {
+							cert, err := a.resolveAnchorCert(certs.Src)
+							if err != nil {
+								continue
+							}
+							if tool := proxycas.Identify(cert, a.extraProxyCAs); tool != "" {
+								ret = append(ret, Risk{
+									Type:      RiskProxyAnchors,
+									Reason:    fmt.Sprintf(`%s contain the default CA of "%s" (subject "%s").`, section, tool, cert.Subject.String()),
+									InNSCFile: true,
+								})
+							}
+						}
+					}
+				}
+			}
+		}
+		{
+			section := fmt.Sprintf("%s domain config", section)
+
+			// The root's inherited state comes from base-config (pin-set is
+			// not a base-config element, so it starts unset).
+			root := resolvedDomainConfig{}
+			if nsc.BaseConfig != nil {
+				root.cleartextTrafficPermitted = nsc.BaseConfig.CleartextTrafficPermitted
+				root.trustAnchors = nsc.BaseConfig.TrustAnchors
+			}
+
+			// domainConfigLines[i] is the source line of the <domain-config>
+			// block domainConfigs[i] came from, in the same preorder as
+			// flattenDomainConfig walks the tree; see DomainConfigLines.
+			domainConfigLines := manifest.DomainConfigLines(a.nscData)
+			lineOf := func(i int) int {
+				if i < len(domainConfigLines) {
+					return domainConfigLines[i]
+				}
+				return 0
+			}
+
+			pinned := false
+			domainConfigs := flattenDomainConfig(nsc.DomainConfig, root)
+			for i, domainConfig := range domainConfigs {
+				section := fmt.Sprintf("%s sub config (index: %d)", section, i)
+				if domainConfig.pinSet != nil {
+					expiration, err := time.Parse("2006-01-02", domainConfig.pinSet.Expiration)
+					if err != nil || time.Until(expiration).Hours() <= 10*24 {
+						// (to be) expired within 10 days
+						ret = append(ret, Risk{
+							Type:      RiskPinningExpiration,
+							Reason:    fmt.Sprintf("%s pin set (will) hit its expiration.", section),
+							InNSCFile: true,
+							NSCLine:   lineOf(i),
+						})
+					}
+
+					if len(domainConfig.pinSet.Pins) > 0 {
+						pinned = true
+					}
+				}
+				for _, domain := range domainConfig.domains {
+					if strings.HasPrefix(domain.Data, "http") {
+						ret = append(ret, Risk{
+							Type:      RiskMalformedNSC,
+							Reason:    fmt.Sprintf(`%s domains contain malformed hostname "%s".`, section, domain.Data),
+							InNSCFile: true,
+							NSCLine:   lineOf(i),
+						})
+					}
+				}
+				if domainConfig.trustAnchors != nil {
+					for _, certs := range domainConfig.trustAnchors.Certificates {
+						if certs.Src != "system" && certs.Src != "user" {
+							cert, err := a.resolveAnchorCert(certs.Src)
+							if err != nil {
+								continue
+							}
+							if tool := proxycas.Identify(cert, a.extraProxyCAs); tool != "" {
+								ret = append(ret, Risk{
+									Type:      RiskProxyAnchors,
+									Reason:    fmt.Sprintf(`%s trust anchors contain the default CA of "%s" (subject "%s").`, section, tool, cert.Subject.String()),
+									InNSCFile: true,
+									NSCLine:   lineOf(i),
+								})
+							}
+						}
+					}
+				}
+			}
+
+			if !pinned {
+				ret = append(ret, Risk{
+					Type:      RiskUnpinned,
+					Reason:    fmt.Sprintf("%s does not contain pinned certificates.", section),
+					InNSCFile: true,
+				})
+			}
+		}
+
+		if app.Debuggable != nil && *app.Debuggable && nsc.DebugOverrides != nil &&
+			nsc.DebugOverrides.TrustAnchors != nil && len(nsc.DebugOverrides.TrustAnchors.Certificates) > 0 &&
+			!looksLikeDebugBuild(app) {
+			ret = append(ret, Risk{
+				Type:      RiskDebugOverridesInRelease,
+				Reason:    fmt.Sprintf("%s debug-overrides supplies trust anchors while the app is debuggable and does not look like a debug build.", section),
+				InNSCFile: true,
+			})
+		}
+	} else {
+		section := "Manifest"
+		if defaults.NSCPresence {
+			ret = append(ret, Risk{
+				Type:   RiskNSCMissing,
+				Reason: fmt.Sprintf("%s does not specify NSC where target Android version supports it.", section),
+			})
+		}
+
+		if app.UsesCleartextTraffic == nil {
+			if defaults.AllowCleartext {
+				ret = append(ret, Risk{
+					Type:   RiskCleartext,
+					Reason: fmt.Sprintf("%s defaults permit cleartext traffic.", section),
+				})
+			}
+		} else if *app.UsesCleartextTraffic {
+			ret = append(ret, Risk{
+				Type:   RiskCleartext,
+				Reason: fmt.Sprintf("%s permits cleartext traffic.", section),
+			})
+		}
+	}
+
+	return
+}
+
+// checkDex scans the APK's DEX bytecode for Java-level TLS-bypass idioms
+// that the manifest/NSC analysis above cannot see (custom TrustManagers,
+// HostnameVerifiers, WebView SSL error handling, and missing OkHttp
+// pinning).
+func (a *Analysis) checkDex() ([]Risk, error) {
+	findings, err := dex.Scan(a.zip)
+	if err != nil {
+		return nil, fmt.Errorf("dex scan error: %w", err)
+	}
+
+	ret := make([]Risk, 0, len(findings))
+	for _, f := range findings {
+		var riskType RiskType
+		switch f.Pattern {
+		case dex.TrustManagerBypass:
+			riskType = RiskTrustManagerBypass
+		case dex.HostnameVerifierBypass:
+			riskType = RiskHostnameVerifierBypass
+		case dex.WebViewSSLBypass:
+			riskType = RiskWebViewSSLBypass
+		case dex.MissingCertificatePinner:
+			riskType = RiskMissingCertificatePinner
+		default:
+			continue
+		}
+		ret = append(ret, Risk{Type: riskType, Reason: f.Detail})
+	}
+	return ret, nil
+}
+
+// checkSignature inspects the APK's signing scheme(s): a v1-only (JAR)
+// signature targeting an SDK version that the Play Store now rejects,
+// debug or expired signing certificates, and a v3 rotation lineage that
+// doesn't trace back to the v2 signer.
+func (a *Analysis) checkSignature() []Risk {
+	ret := make([]Risk, 0)
+	sig := a.sigInfo
+	if sig == nil {
+		return ret
+	}
+
+	targetSDK := a.m.UsesSDK.TargetSDKVersion
+	if sig.HasV1 && !sig.HasV2() && !sig.HasV3() && targetSDK >= 30 {
+		ret = append(ret, Risk{
+			Type:   RiskSignedV1Only,
+			Reason: fmt.Sprintf("APK is signed with only the v1 (JAR) scheme, but targets SDK %d (>= 30), which Google Play now requires v2+ for.", targetSDK),
+		})
+	}
+
+	certs := append(append([]*x509.Certificate{}, sig.V2Certificates...), sig.V3Certificates...)
+	for _, cert := range certs {
+		switch {
+		case isAndroidDebugCert(cert):
+			ret = append(ret, Risk{
+				Type:   RiskDebugSigningCert,
+				Reason: fmt.Sprintf(`signing certificate "%s" matches the Android debug key.`, cert.Subject.String()),
+			})
+		case cert.NotAfter.Before(time.Now()):
+			ret = append(ret, Risk{
+				Type:   RiskDebugSigningCert,
+				Reason: fmt.Sprintf(`signing certificate "%s" expired on %s.`, cert.Subject.String(), cert.NotAfter.Format("2006-01-02")),
+			})
+		}
+	}
+
+	if sig.HasV2() && sig.HasV3() {
+		ancestors := sig.V3LineageCertificates
+		if len(ancestors) == 0 {
+			ancestors = sig.V3Certificates
+		}
+		if !certsOverlap(sig.V2Certificates, ancestors) {
+			ret = append(ret, Risk{
+				Type:   RiskRotatedKeyMismatch,
+				Reason: "v3 signing lineage does not include the v2 signer's certificate; the app may have been re-signed with an unrelated key.",
+			})
+		}
+	}
+
+	return ret
+}
+
+// isAndroidDebugCert reports whether cert's subject matches the Android
+// SDK's default debug-keystore certificate (CN=Android Debug, O=Android,
+// C=US), which Android Studio generates for every developer and must never
+// sign a release build.
+func isAndroidDebugCert(cert *x509.Certificate) bool {
+	return cert.Subject.CommonName == "Android Debug" &&
+		containsString(cert.Subject.Organization, "Android") &&
+		containsString(cert.Subject.Country, "US")
+}
+
+func containsString(haystack []string, want string) bool {
+	for _, s := range haystack {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// certsOverlap reports whether any certificate in a also appears in b, by
+// raw DER bytes.
+func certsOverlap(a, b []*x509.Certificate) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if string(x.Raw) == string(y.Raw) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (a *Analysis) String() string {
+	risks := []string{}
+	for i, risk := range a.Risks {
+		risks = append(risks, fmt.Sprintf("    %d. %s", i+1, risk.String()))
+	}
+	return strings.TrimPrefix(fmt.Sprintf(`
+File    : %s
+Name    : %s
+Version : Android %d (target)
+Risks   :
+%s`, a.File, a.Name, a.TargetVersion, strings.Join(risks, "\n")), "\n")
+}
+
+// NSCFile returns the in-APK path of the Network Security Config this
+// analysis parsed, or "" if the app doesn't declare one.
+func (a *Analysis) NSCFile() string {
+	return a.nscFile
+}
+
+// nscData is the NSC file's raw (already-decoded-from-binary-XML) bytes,
+// used only to locate the line of the <domain-config> block a risk was
+// raised against; pass nil if unavailable (nsc must then also be nil).
+func Analyze(file string, m *manifest.Manifest, nsc *manifest.NetworkSecurityConfig, nscFile string, nscData []byte, extraProxyCAs map[string]string, sigInfo *apksig.Info, zip *apkparser.ZipReader, resources *apkparser.ResourceTable) (*Analysis, error) {
+	ret := &Analysis{
+		File:          file,
+		Name:          m.Application.Name,
+		TargetVersion: SDKVersionToAndroidMajor(m.UsesSDK.TargetSDKVersion),
+		m:             m,
+		nsc:           nsc,
+		nscFile:       nscFile,
+		nscData:       nscData,
+		extraProxyCAs: extraProxyCAs,
+		sigInfo:       sigInfo,
+		zip:           zip,
+		resources:     resources,
+	}
+
+	// Android 7+ supports NSC
+	// Android 9+ disables cleartext traffic by default
+
+	ret.defaults = AndroidDefaults{
+		AllowCleartext: ret.TargetVersion < 9,
+		NSCPresence:    ret.TargetVersion >= 7,
+	}
+
+	ret.Risks = ret.check()
+
+	dexRisks, err := ret.checkDex()
+	if err != nil {
+		log.Printf(`dex scan error for "%s": %v`, file, err)
+	} else {
+		ret.Risks = append(ret.Risks, dexRisks...)
+	}
+
+	ret.Risks = append(ret.Risks, ret.checkSignature()...)
+
+	return ret, nil
+}
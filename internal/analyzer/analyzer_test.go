@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/sigeryang/tlshunter/internal/manifest"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestFlattenDomainConfigSkipsNilChild(t *testing.T) {
+	domainConfigs := []*manifest.DomainConfig{
+		{Domains: []*manifest.Domain{{Data: "example.com"}}},
+		nil,
+	}
+
+	got := flattenDomainConfig(domainConfigs, resolvedDomainConfig{})
+
+	if len(got) != 1 {
+		t.Fatalf("flattenDomainConfig returned %d entries, want 1 (nil child skipped): %+v", len(got), got)
+	}
+}
+
+func TestFlattenDomainConfigInheritance(t *testing.T) {
+	parentAnchors := &manifest.TrustAnchors{Certificates: []*manifest.Certificates{{Src: "@raw/parent_ca"}}}
+	parentPins := &manifest.PinSet{Expiration: "2099-01-01"}
+
+	domainConfigs := []*manifest.DomainConfig{
+		{
+			TrustAnchors: parentAnchors,
+			PinSet:       parentPins,
+			Children: []*manifest.DomainConfig{
+				// Child declares neither trust-anchors nor pin-set, so both
+				// should be inherited from the parent once flattened.
+				{Domains: []*manifest.Domain{{Data: "sub.example.com"}}},
+			},
+		},
+	}
+
+	got := flattenDomainConfig(domainConfigs, resolvedDomainConfig{})
+
+	if len(got) != 2 {
+		t.Fatalf("flattenDomainConfig returned %d entries, want 2 (parent + child)", len(got))
+	}
+	child := got[1]
+	if child.trustAnchors != parentAnchors {
+		t.Errorf("child.trustAnchors = %v, want inherited parent anchors %v", child.trustAnchors, parentAnchors)
+	}
+	if child.pinSet != parentPins {
+		t.Errorf("child.pinSet = %v, want inherited parent pin set %v", child.pinSet, parentPins)
+	}
+}
+
+func hasRiskType(risks []Risk, t RiskType) bool {
+	for _, r := range risks {
+		if r.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCheckDebugOverridesInRelease(t *testing.T) {
+	baseNSC := func() *manifest.NetworkSecurityConfig {
+		return &manifest.NetworkSecurityConfig{
+			BaseConfig: &manifest.BaseConfig{CleartextTrafficPermitted: boolPtr(false)},
+			DebugOverrides: &manifest.DebugOverrides{
+				TrustAnchors: &manifest.TrustAnchors{
+					Certificates: []*manifest.Certificates{{Src: "user"}},
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		name      string
+		app       manifest.Application
+		nsc       *manifest.NetworkSecurityConfig
+		wantDebug bool
+	}{
+		{
+			name:      "debuggable release build flags debug-overrides",
+			app:       manifest.Application{Debuggable: boolPtr(true)},
+			nsc:       baseNSC(),
+			wantDebug: true,
+		},
+		{
+			name:      "debuggable build named .debug is not flagged",
+			app:       manifest.Application{Debuggable: boolPtr(true), Name: "com.example.app.debug"},
+			nsc:       baseNSC(),
+			wantDebug: false,
+		},
+		{
+			name:      "non-debuggable app is not flagged",
+			app:       manifest.Application{Debuggable: boolPtr(false)},
+			nsc:       baseNSC(),
+			wantDebug: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := &Analysis{
+				m:   &manifest.Manifest{Application: c.app},
+				nsc: c.nsc,
+			}
+			got := hasRiskType(a.check(), RiskDebugOverridesInRelease)
+			if got != c.wantDebug {
+				t.Errorf("RiskDebugOverridesInRelease present = %v, want %v", got, c.wantDebug)
+			}
+		})
+	}
+}
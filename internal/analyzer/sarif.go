@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"github.com/sigeryang/tlshunter/internal/report"
+)
+
+// RiskLevel maps a RiskType to the SARIF severity level it is reported at.
+func RiskLevel(t RiskType) report.Level {
+	switch t {
+	case RiskCleartext, RiskUserAnchors:
+		return report.LevelWarning
+	case RiskUnpinned, RiskProxyAnchors:
+		return report.LevelError
+	case RiskMalformedNSC, RiskPinningExpiration:
+		return report.LevelNote
+	case RiskAnchorsOverridePinning, RiskTrustManagerBypass, RiskHostnameVerifierBypass, RiskWebViewSSLBypass, RiskMissingCertificatePinner, RiskDebugOverridesInRelease:
+		return report.LevelError
+	case RiskSignedV1Only, RiskDebugSigningCert:
+		return report.LevelWarning
+	case RiskRotatedKeyMismatch:
+		return report.LevelError
+	default:
+		return report.LevelWarning
+	}
+}
+
+// BuildSARIF converts a batch of analyses into a SARIF 2.1.0 log.
+func BuildSARIF(analyses []*Analysis) interface{} {
+	rules := make([]report.Rule, 0, len(AllRiskTypes))
+	for _, t := range AllRiskTypes {
+		rules = append(rules, report.Rule{ID: t.ID(), ShortDescription: t.Description()})
+	}
+
+	var findings []report.Finding
+	for _, a := range analyses {
+		for _, risk := range a.Risks {
+			innerPath := ""
+			if risk.InNSCFile {
+				innerPath = a.nscFile
+			}
+			findings = append(findings, report.Finding{
+				RuleID:         risk.Type.ID(),
+				Level:          RiskLevel(risk.Type),
+				Message:        risk.Reason,
+				Artifact:       a.File,
+				InnerPath:      innerPath,
+				InnerStartLine: risk.NSCLine,
+			})
+		}
+	}
+
+	return report.Build(rules, findings)
+}
@@ -0,0 +1,180 @@
+package apksig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHasV1SignatureFile(t *testing.T) {
+	cases := []struct {
+		name  string
+		names []string
+		want  bool
+	}{
+		{"no META-INF at all", []string{"AndroidManifest.xml", "classes.dex"}, false},
+		{"META-INF present but not a signature file", []string{"META-INF/MANIFEST.MF"}, false},
+		{"RSA signature file", []string{"META-INF/CERT.RSA"}, true},
+		{"DSA signature file", []string{"META-INF/CERT.DSA"}, true},
+		{"EC signature file", []string{"META-INF/CERT.EC"}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := hasV1SignatureFile(c.names); got != c.want {
+				t.Errorf("hasV1SignatureFile(%v) = %v, want %v", c.names, got, c.want)
+			}
+		})
+	}
+}
+
+// writeLP appends a uint32-length-prefixed copy of content to buf.
+func writeLP(buf []byte, content []byte) []byte {
+	var lenBytes [4]byte
+	binary.LittleEndian.PutUint32(lenBytes[:], uint32(len(content)))
+	buf = append(buf, lenBytes[:]...)
+	return append(buf, content...)
+}
+
+// buildV2Value assembles a v2/v3 Block value (the bytes parseSigners
+// decodes) carrying a single signer with a single certificate and no
+// additional attributes.
+func buildV2Value(certDER []byte) []byte {
+	var digests []byte // empty: parseSignedData only skips over this
+
+	var certsSeq []byte
+	certsSeq = writeLP(certsSeq, certDER)
+
+	var attrsSeq []byte // empty
+
+	var signedData []byte
+	signedData = writeLP(signedData, digests)
+	signedData = writeLP(signedData, certsSeq)
+	signedData = writeLP(signedData, attrsSeq)
+
+	var signer []byte
+	signer = writeLP(signer, signedData)
+
+	var signerSeq []byte
+	signerSeq = writeLP(signerSeq, signer)
+
+	return writeLP(nil, signerSeq)
+}
+
+// generateSelfSigned returns a DER-encoded self-signed certificate with the
+// given common name.
+func generateSelfSigned(t *testing.T, cn string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).AddDate(30, 0, 0),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	return der
+}
+
+// buildTestAPK assembles a minimal zip-shaped file: an arbitrary prefix, an
+// APK Signing Block whose only pair is (idSignatureV2, v2Value), an empty
+// Central Directory, and the End Of Central Directory record pointing at
+// it - exactly what Inspect scans backward from the end of the file to
+// find.
+func buildTestAPK(v2Value []byte) []byte {
+	data := []byte("not a real local file header, just filler\x00")
+
+	var pairs []byte
+	pairs = append(pairs, encodePair(idSignatureV2, v2Value)...)
+
+	blockSize := uint64(len(pairs) + 24)
+	var trailingSize [8]byte
+	binary.LittleEndian.PutUint64(trailingSize[:], blockSize)
+
+	data = append(data, pairs...)
+	data = append(data, trailingSize[:]...)
+	data = append(data, []byte(sigBlockMagic)...)
+
+	centralDirOffset := uint32(len(data))
+	// Central Directory itself is empty; the EOCD immediately follows.
+
+	eocd := make([]byte, 22)
+	binary.LittleEndian.PutUint32(eocd[0:], eocdMagic)
+	binary.LittleEndian.PutUint32(eocd[16:], centralDirOffset)
+	data = append(data, eocd...)
+
+	return data
+}
+
+func encodePair(id uint32, value []byte) []byte {
+	var out []byte
+	var lenBytes [8]byte
+	binary.LittleEndian.PutUint64(lenBytes[:], uint64(4+len(value)))
+	out = append(out, lenBytes[:]...)
+	var idBytes [4]byte
+	binary.LittleEndian.PutUint32(idBytes[:], id)
+	out = append(out, idBytes[:]...)
+	return append(out, value...)
+}
+
+func TestInspectV2SigningBlock(t *testing.T) {
+	certDER := generateSelfSigned(t, "tlshunter-test")
+	data := buildTestAPK(buildV2Value(certDER))
+
+	path := filepath.Join(t.TempDir(), "test.apk")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	info, err := Inspect(path, nil)
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if info.HasV1 {
+		t.Errorf("HasV1 = true, want false (no META-INF signature files given)")
+	}
+	if !info.HasV2() {
+		t.Fatalf("HasV2() = false, want true")
+	}
+	if info.HasV3() {
+		t.Errorf("HasV3() = true, want false")
+	}
+	if got := info.V2Certificates[0].Subject.CommonName; got != "tlshunter-test" {
+		t.Errorf("V2Certificates[0].Subject.CommonName = %q, want %q", got, "tlshunter-test")
+	}
+}
+
+func TestInspectNoSigningBlock(t *testing.T) {
+	eocd := make([]byte, 22)
+	binary.LittleEndian.PutUint32(eocd[0:], eocdMagic)
+	binary.LittleEndian.PutUint32(eocd[16:], 0) // empty central directory at offset 0
+
+	path := filepath.Join(t.TempDir(), "unsigned.apk")
+	if err := os.WriteFile(path, eocd, 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	info, err := Inspect(path, []string{"META-INF/CERT.RSA"})
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if !info.HasV1 {
+		t.Errorf("HasV1 = false, want true")
+	}
+	if info.HasV2() || info.HasV3() {
+		t.Errorf("HasV2()/HasV3() = true, want both false: %+v", info)
+	}
+}
@@ -0,0 +1,159 @@
+package apksig
+
+import (
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+)
+
+// lineageAttrID is the v3 "proof of rotation" additional attribute that
+// records the full signer history, so verifiers can trust an app signed by
+// a newer key if it trusts any key in the key's rotation lineage.
+const lineageAttrID uint32 = 0x3ba06f8c
+
+// parseSigners decodes a v2/v3 Block value: a length-prefixed sequence of
+// length-prefixed signers. It returns the leaf certificate of each signer's
+// signed data, plus the additional attributes of the first signer (v2/v3
+// blocks carry exactly one signer in the overwhelming majority of APKs, and
+// the proof-of-rotation lineage this package cares about is only ever
+// attached to the single, current v3 signer).
+func parseSigners(value []byte) ([]*x509.Certificate, map[uint32][]byte, error) {
+	seq, err := lengthPrefixed(value, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("signer sequence: %w", err)
+	}
+
+	var certs []*x509.Certificate
+	var firstAttrs map[uint32][]byte
+	for pos := 0; pos < len(seq); {
+		signer, n, err := readLengthPrefixed(seq, pos)
+		if err != nil {
+			return nil, nil, fmt.Errorf("signer: %w", err)
+		}
+		pos += n
+
+		signedData, err := lengthPrefixed(signer, 0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("signed data: %w", err)
+		}
+
+		signerCerts, attrs, err := parseSignedData(signedData)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(signerCerts) > 0 {
+			certs = append(certs, signerCerts[0])
+		}
+		if firstAttrs == nil {
+			firstAttrs = attrs
+		}
+	}
+
+	return certs, firstAttrs, nil
+}
+
+// parseSignedData decodes a signer's signed-data structure: a
+// length-prefixed digest sequence (skipped, since we don't verify
+// signatures), a length-prefixed certificate sequence, and a length-prefixed
+// additional-attribute sequence. Trailing bytes (v3's minSDK/maxSDK) are
+// ignored.
+func parseSignedData(buf []byte) ([]*x509.Certificate, map[uint32][]byte, error) {
+	_, n, err := readLengthPrefixed(buf, 0) // digests
+	if err != nil {
+		return nil, nil, fmt.Errorf("digests: %w", err)
+	}
+	pos := n
+
+	certsSeq, n, err := readLengthPrefixed(buf, pos)
+	if err != nil {
+		return nil, nil, fmt.Errorf("certificates: %w", err)
+	}
+	pos += n
+
+	var certs []*x509.Certificate
+	for cpos := 0; cpos < len(certsSeq); {
+		der, n, err := readLengthPrefixed(certsSeq, cpos)
+		if err != nil {
+			return nil, nil, fmt.Errorf("certificate: %w", err)
+		}
+		cpos += n
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	attrs := map[uint32][]byte{}
+	if attrsSeq, n, err := readLengthPrefixed(buf, pos); err == nil {
+		pos += n
+		for apos := 0; apos < len(attrsSeq); {
+			entry, n, err := readLengthPrefixed(attrsSeq, apos)
+			if err != nil || len(entry) < 4 {
+				break
+			}
+			apos += n
+			id := binary.LittleEndian.Uint32(entry)
+			attrs[id] = entry[4:]
+		}
+	}
+
+	return certs, attrs, nil
+}
+
+// lineageCertificates decodes the v3 proof-of-rotation lineage attribute
+// (if present) into its signer certificates, oldest first. The value is a
+// uint32 version followed by length-prefixed records. Each record starts
+// with a length-prefixed signed-data block (a length-prefixed certificate
+// plus a signature algorithm ID), followed by capability flags, a
+// signature algorithm ID and a length-prefixed signature, which this
+// package does not verify.
+func lineageCertificates(attrs map[uint32][]byte) []*x509.Certificate {
+	value, ok := attrs[lineageAttrID]
+	if !ok {
+		return nil
+	}
+
+	var certs []*x509.Certificate
+	for pos := 4; pos+4 <= len(value); {
+		record, n, err := readLengthPrefixed(value, pos)
+		if err != nil {
+			break
+		}
+		pos += n
+
+		signedData, _, err := readLengthPrefixed(record, 0)
+		if err != nil {
+			continue
+		}
+		der, _, err := readLengthPrefixed(signedData, 0)
+		if err != nil {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(der); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+	return certs
+}
+
+// readLengthPrefixed reads a uint32-length-prefixed byte string starting at
+// off, returning the string and the number of bytes consumed (4 + length).
+func readLengthPrefixed(buf []byte, off int) ([]byte, int, error) {
+	if off+4 > len(buf) {
+		return nil, 0, fmt.Errorf("truncated length prefix")
+	}
+	length := int(binary.LittleEndian.Uint32(buf[off:]))
+	start := off + 4
+	if length < 0 || start+length > len(buf) {
+		return nil, 0, fmt.Errorf("length %d out of range", length)
+	}
+	return buf[start : start+length], 4 + length, nil
+}
+
+// lengthPrefixed reads the single length-prefixed string at off and
+// discards the consumed-byte count, for callers that only expect one.
+func lengthPrefixed(buf []byte, off int) ([]byte, error) {
+	v, _, err := readLengthPrefixed(buf, off)
+	return v, err
+}
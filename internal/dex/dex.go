@@ -0,0 +1,298 @@
+// Package dex provides a lightweight reader for the Android DEX bytecode
+// format, sufficient for pattern-matching known TLS-bypass idioms without
+// doing a full SSA lift.
+//
+// Reference: https://source.android.com/docs/core/runtime/dex-format
+package dex
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const headerSize = 112
+
+var dexMagicPrefix = [4]byte{'d', 'e', 'x', '\n'}
+
+// File is a parsed DEX file, resolved down to classes and their methods.
+type File struct {
+	Classes []Class
+
+	// Strings holds every decoded entry of the string pool, including ones
+	// never referenced by a class (e.g. field/constant names kept only for
+	// reflection). Callers use this for coarse string-literal matching.
+	Strings []string
+}
+
+// Class is a single class_def_item together with its methods.
+type Class struct {
+	Name       string
+	Super      string
+	Interfaces []string
+	Methods    []Method
+}
+
+// Method is a single encoded_method, with its bytecode decoded into raw
+// 16-bit code units (instructions) and its invoke-kind call targets already
+// resolved.
+type Method struct {
+	Name         string
+	Virtual      bool
+	Instructions []uint16
+	Invokes      []Invoke
+}
+
+// Invoke is a resolved invoke-kind target: the owning class and method name
+// referenced by an invoke-virtual/-interface/-super/-static/-direct (or
+// their /range forms).
+type Invoke struct {
+	Class  string
+	Method string
+}
+
+type header struct {
+	stringIDsSize, stringIDsOff uint32
+	typeIDsSize, typeIDsOff     uint32
+	methodIDsSize, methodIDsOff uint32
+	classDefsSize, classDefsOff uint32
+}
+
+type methodID struct {
+	classIdx uint16
+	protoIdx uint16
+	nameIdx  uint32
+}
+
+// Parse decodes a raw classes.dex image into a File.
+func Parse(data []byte) (*File, error) {
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("dex: file too small (%d bytes)", len(data))
+	}
+	if [4]byte{data[0], data[1], data[2], data[3]} != dexMagicPrefix {
+		return nil, fmt.Errorf("dex: bad magic")
+	}
+
+	// Offsets per the DEX header layout; see the format reference above.
+	h := header{
+		stringIDsSize: le32(data, 56), stringIDsOff: le32(data, 60),
+		typeIDsSize: le32(data, 64), typeIDsOff: le32(data, 68),
+		methodIDsSize: le32(data, 88), methodIDsOff: le32(data, 92),
+		classDefsSize: le32(data, 96), classDefsOff: le32(data, 100),
+	}
+
+	strings, err := readStrings(data, h.stringIDsOff, h.stringIDsSize)
+	if err != nil {
+		return nil, fmt.Errorf("dex: read strings: %w", err)
+	}
+
+	typeIDs := make([]uint32, h.typeIDsSize)
+	for i := range typeIDs {
+		typeIDs[i] = le32(data, int(h.typeIDsOff)+4*i)
+	}
+	typeName := func(idx uint32) string {
+		if int(idx) >= len(typeIDs) {
+			return ""
+		}
+		return strings.get(typeIDs[idx])
+	}
+
+	methodIDs := make([]methodID, h.methodIDsSize)
+	for i := range methodIDs {
+		off := int(h.methodIDsOff) + 8*i
+		methodIDs[i] = methodID{
+			classIdx: binary.LittleEndian.Uint16(data[off:]),
+			protoIdx: binary.LittleEndian.Uint16(data[off+2:]),
+			nameIdx:  le32(data, off+4),
+		}
+	}
+	methodName := func(idx uint32) string {
+		if int(idx) >= len(methodIDs) {
+			return ""
+		}
+		return strings.get(methodIDs[idx].nameIdx)
+	}
+	methodOwner := func(idx uint32) string {
+		if int(idx) >= len(methodIDs) {
+			return ""
+		}
+		return typeName(uint32(methodIDs[idx].classIdx))
+	}
+
+	f := &File{}
+	for i := uint32(0); i < h.classDefsSize; i++ {
+		off := int(h.classDefsOff) + 32*int(i)
+		classIdx := le32(data, off)
+		superclassIdx := le32(data, off+8)
+		interfacesOff := le32(data, off+12)
+		classDataOff := le32(data, off+24)
+
+		class := Class{
+			Name:       typeName(classIdx),
+			Super:      typeName(superclassIdx),
+			Interfaces: readTypeList(data, interfacesOff, typeName),
+		}
+		if classDataOff != 0 {
+			methods, err := readClassData(data, int(classDataOff), methodName, methodOwner)
+			if err != nil {
+				return nil, fmt.Errorf("dex: class %s: %w", class.Name, err)
+			}
+			class.Methods = methods
+		}
+		f.Classes = append(f.Classes, class)
+	}
+
+	f.Strings = make([]string, len(strings.offsets))
+	for i := range f.Strings {
+		f.Strings[i] = strings.get(uint32(i))
+	}
+
+	return f, nil
+}
+
+// readTypeList decodes a type_list (used for interfaces, throws, and
+// parameters) into its resolved type names. An offset of 0 means absent.
+func readTypeList(data []byte, off uint32, typeName func(uint32) string) []string {
+	if off == 0 {
+		return nil
+	}
+	size := le32(data, int(off))
+	types := make([]string, 0, size)
+	for i := uint32(0); i < size; i++ {
+		idx := uint32(binary.LittleEndian.Uint16(data[int(off)+4+2*int(i):]))
+		types = append(types, typeName(idx))
+	}
+	return types
+}
+
+// readClassData decodes the encoded_method lists of a class_data_item and
+// resolves each encoded method's bytecode.
+func readClassData(data []byte, off int, methodName, methodOwner func(uint32) string) ([]Method, error) {
+	r := &uleb128Reader{data: data, pos: off}
+
+	staticFieldsSize, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	instanceFieldsSize, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	directMethodsSize, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	virtualMethodsSize, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip encoded_field lists; only the counts matter to reach the methods.
+	for i := uint64(0); i < staticFieldsSize+instanceFieldsSize; i++ {
+		if _, err := r.read(); err != nil { // field_idx_diff
+			return nil, err
+		}
+		if _, err := r.read(); err != nil { // access_flags
+			return nil, err
+		}
+	}
+
+	var methods []Method
+	readMethods := func(count uint64, virtual bool) error {
+		methodIdx := uint64(0)
+		for i := uint64(0); i < count; i++ {
+			diff, err := r.read()
+			if err != nil {
+				return err
+			}
+			methodIdx += diff
+			if _, err := r.read(); err != nil { // access_flags
+				return err
+			}
+			codeOff, err := r.read()
+			if err != nil {
+				return err
+			}
+
+			m := Method{Name: methodName(uint32(methodIdx)), Virtual: virtual}
+			if codeOff != 0 {
+				insns, err := readCodeItem(data, int(codeOff))
+				if err != nil {
+					return err
+				}
+				m.Instructions = insns
+				m.Invokes = extractInvokes(insns, methodName, methodOwner)
+			}
+			methods = append(methods, m)
+		}
+		return nil
+	}
+
+	if err := readMethods(directMethodsSize, false); err != nil {
+		return nil, err
+	}
+	if err := readMethods(virtualMethodsSize, true); err != nil {
+		return nil, err
+	}
+
+	return methods, nil
+}
+
+// readCodeItem decodes a code_item's instruction stream into 16-bit units.
+func readCodeItem(data []byte, off int) ([]uint16, error) {
+	if off+16 > len(data) {
+		return nil, fmt.Errorf("code_item offset out of range")
+	}
+	insnsSize := le32(data, off+12)
+	start := off + 16
+	end := start + int(insnsSize)*2
+	if end > len(data) {
+		return nil, fmt.Errorf("code_item instructions out of range")
+	}
+	insns := make([]uint16, insnsSize)
+	for i := range insns {
+		insns[i] = binary.LittleEndian.Uint16(data[start+2*i:])
+	}
+	return insns, nil
+}
+
+// invoke-kind opcodes (formats 35c and 3rc both carry the method index in
+// the code unit immediately following the opcode unit).
+var invokeOpcodes = map[byte]bool{
+	0x6e: true, // invoke-virtual
+	0x6f: true, // invoke-super
+	0x70: true, // invoke-direct
+	0x71: true, // invoke-static
+	0x72: true, // invoke-interface
+	0x74: true, // invoke-virtual/range
+	0x75: true, // invoke-super/range
+	0x76: true, // invoke-direct/range
+	0x77: true, // invoke-static/range
+	0x78: true, // invoke-interface/range
+}
+
+// extractInvokes does a single linear pass over a method's instruction
+// stream, resolving every invoke-kind call site to its target class/method.
+// This is pattern-matching only: it does not track dataflow between calls.
+// Every instruction is skipped by its real width (see instrWidth), not just
+// invoke-kind ones, since misjudging any other opcode's width would desync
+// the rest of the scan and start decoding operand words as opcodes.
+func extractInvokes(insns []uint16, methodName, methodOwner func(uint32) string) []Invoke {
+	var invokes []Invoke
+	for i := 0; i < len(insns); {
+		op := byte(insns[i] & 0xff)
+		width := instrWidth(insns, i)
+		if invokeOpcodes[op] && i+1 < len(insns) {
+			methodIdx := uint32(insns[i+1])
+			invokes = append(invokes, Invoke{Class: methodOwner(methodIdx), Method: methodName(methodIdx)})
+		}
+		if width < 1 {
+			width = 1
+		}
+		i += width
+	}
+	return invokes
+}
+
+func le32(data []byte, off int) uint32 {
+	return binary.LittleEndian.Uint32(data[off:])
+}
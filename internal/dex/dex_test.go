@@ -0,0 +1,205 @@
+package dex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildTestDex assembles a minimal but structurally valid classes.dex image
+// containing a single class with a single direct method "m" whose code_item
+// holds insns verbatim, plus a second method_ids entry ("target", owned by
+// the same class) that a test's invoke operand can reference. It only fills
+// in the header fields Parse actually reads.
+func buildTestDex(insns []uint16) []byte {
+	const (
+		emptyStrIdx   = 0
+		classStrIdx   = 1
+		methodStrIdx  = 2
+		targetStrIdx  = 3
+		dummyStrIdx   = 110 // chosen so its low byte (0x6e) collides with invoke-virtual
+		stringIDCount = dummyStrIdx + 1
+	)
+
+	uleb := func(v uint64) []byte {
+		var out []byte
+		for {
+			b := byte(v & 0x7f)
+			v >>= 7
+			if v != 0 {
+				b |= 0x80
+			}
+			out = append(out, b)
+			if v == 0 {
+				break
+			}
+		}
+		return out
+	}
+	stringData := func(s string) []byte {
+		out := uleb(uint64(len(s)))
+		out = append(out, []byte(s)...)
+		return append(out, 0) // NUL terminator
+	}
+
+	var body bytes.Buffer
+	off := func() uint32 { return uint32(headerSize + body.Len()) }
+
+	// string_data_items: only the indices the test cares about get real
+	// content; every other string_ids slot (Parse resolves all of them
+	// while building File.Strings) points at the empty one.
+	emptyOff := off()
+	body.Write(stringData(""))
+	classOff := off()
+	body.Write(stringData("Lfoo/Bar;"))
+	methodOff := off()
+	body.Write(stringData("m"))
+	targetOff := off()
+	body.Write(stringData("target"))
+	dummyOff := off()
+	body.Write(stringData("dummy"))
+
+	// code_item
+	codeOff := off()
+	var u16buf [2]byte
+	var u32buf [4]byte
+	writeU16 := func(v uint16) {
+		binary.LittleEndian.PutUint16(u16buf[:], v)
+		body.Write(u16buf[:])
+	}
+	writeU32 := func(v uint32) {
+		binary.LittleEndian.PutUint32(u32buf[:], v)
+		body.Write(u32buf[:])
+	}
+	writeU16(0) // registers_size
+	writeU16(0) // ins_size
+	writeU16(0) // outs_size
+	writeU16(0) // tries_size
+	writeU32(0) // debug_info_off
+	writeU32(uint32(len(insns)))
+	for _, insn := range insns {
+		writeU16(insn)
+	}
+
+	// class_data_item: 0 static fields, 0 instance fields, 1 direct
+	// method ("m", method_idx 0), 0 virtual methods.
+	classDataOff := off()
+	body.Write(uleb(0))
+	body.Write(uleb(0))
+	body.Write(uleb(1))
+	body.Write(uleb(0))
+	body.Write(uleb(0))        // method_idx_diff -> method_idx 0
+	body.Write(uleb(0))        // access_flags
+	body.Write(uleb(uint64(codeOff)))
+
+	// string_ids: array of uint32 offsets, one per index 0..stringIDCount-1
+	stringIDsOff := off()
+	for i := 0; i < stringIDCount; i++ {
+		switch i {
+		case classStrIdx:
+			writeU32(classOff)
+		case methodStrIdx:
+			writeU32(methodOff)
+		case targetStrIdx:
+			writeU32(targetOff)
+		case dummyStrIdx:
+			writeU32(dummyOff)
+		default:
+			writeU32(emptyOff)
+		}
+	}
+
+	// type_ids: a single type, "Lfoo/Bar;"
+	typeIDsOff := off()
+	writeU32(classStrIdx)
+
+	// method_ids: method 0 is "m" (the method under test), method 1 is
+	// "target" (an invoke operand can reference it as a call target).
+	methodIDsOff := off()
+	writeU16(0) // classIdx
+	writeU16(0) // protoIdx
+	writeU32(methodStrIdx)
+	writeU16(0) // classIdx
+	writeU16(0) // protoIdx
+	writeU32(targetStrIdx)
+
+	// class_defs: a single class
+	classDefsOff := off()
+	writeU32(0) // class_idx (type 0)
+	writeU32(0) // access_flags
+	writeU32(0) // superclass_idx
+	writeU32(0) // interfaces_off (none)
+	writeU32(0) // source_file_idx
+	writeU32(0) // annotations_off
+	writeU32(classDataOff)
+	writeU32(0) // static_values_off
+
+	header := make([]byte, headerSize)
+	copy(header, dexMagicPrefix[:])
+	binary.LittleEndian.PutUint32(header[56:], uint32(stringIDCount))
+	binary.LittleEndian.PutUint32(header[60:], stringIDsOff)
+	binary.LittleEndian.PutUint32(header[64:], 1)
+	binary.LittleEndian.PutUint32(header[68:], typeIDsOff)
+	binary.LittleEndian.PutUint32(header[88:], 2)
+	binary.LittleEndian.PutUint32(header[92:], methodIDsOff)
+	binary.LittleEndian.PutUint32(header[96:], 1)
+	binary.LittleEndian.PutUint32(header[100:], classDefsOff)
+
+	return append(header, body.Bytes()...)
+}
+
+// TestParseNoPhantomInvoke reproduces a method whose only instructions are
+// `const-string v0, "dummy"; return-void` with no invoke anywhere. The
+// const-string operand (string index 110 = 0x006e) is chosen so its low
+// byte collides with the invoke-virtual opcode: a parser that advances one
+// code unit at a time instead of by each instruction's real width would
+// decode a phantom invoke-virtual here.
+func TestParseNoPhantomInvoke(t *testing.T) {
+	insns := []uint16{
+		0x001a, // const-string v0, string@110
+		0x006e,
+		0x000e, // return-void
+	}
+	f, err := Parse(buildTestDex(insns))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	m := soleMethod(t, f)
+	if len(m.Invokes) != 0 {
+		t.Fatalf("Invokes = %v, want none", m.Invokes)
+	}
+}
+
+// TestParseInvokeAfterWideInstruction verifies a real invoke-virtual is
+// still decoded correctly when it follows a const-string (a 2-code-unit
+// instruction): the fix must skip the full width of every instruction, not
+// just invoke-kind ones, to keep the scan aligned.
+func TestParseInvokeAfterWideInstruction(t *testing.T) {
+	insns := []uint16{
+		0x001a, // const-string v0, string@5 (filler; not a resolvable index)
+		0x0005,
+		0x006e, // invoke-virtual {}, target@1
+		0x0001,
+		0x0000,
+		0x000e, // return-void
+	}
+	f, err := Parse(buildTestDex(insns))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	m := soleMethod(t, f)
+	if len(m.Invokes) != 1 {
+		t.Fatalf("Invokes = %v, want exactly one", m.Invokes)
+	}
+	if got := m.Invokes[0]; got.Class != "Lfoo/Bar;" || got.Method != "target" {
+		t.Fatalf("Invokes[0] = %+v, want {Lfoo/Bar; target}", got)
+	}
+}
+
+func soleMethod(t *testing.T, f *File) Method {
+	t.Helper()
+	if len(f.Classes) != 1 || len(f.Classes[0].Methods) != 1 {
+		t.Fatalf("parsed %d classes, want 1 class with 1 method", len(f.Classes))
+	}
+	return f.Classes[0].Methods[0]
+}
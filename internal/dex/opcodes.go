@@ -0,0 +1,140 @@
+package dex
+
+// instrWidth returns the number of 16-bit code units occupied by the
+// instruction at insns[i], so callers can walk the stream without
+// desyncing on any opcode, not just invoke-kind ones.
+//
+// Most Dalvik opcodes have a fixed width determined solely by the opcode
+// byte (their "format", e.g. 11n, 22c, 35c - see the format reference in
+// dex.go); widthTable below is keyed by opcode for exactly that case. The
+// three payload pseudo-instructions (packed-switch-payload,
+// sparse-switch-payload, fill-array-data-payload) are the exception: they
+// are variable-length and are only ever reached via a 0x00 code unit whose
+// low byte looks like nop, so they're detected by the full unit value and
+// sized from their own embedded element count.
+func instrWidth(insns []uint16, i int) int {
+	unit := insns[i]
+	if unit&0xff == 0x00 && unit != 0x0000 {
+		switch unit {
+		case 0x0100:
+			return packedSwitchPayloadWidth(insns, i)
+		case 0x0200:
+			return sparseSwitchPayloadWidth(insns, i)
+		case 0x0300:
+			return fillArrayDataPayloadWidth(insns, i)
+		}
+	}
+	return int(widthTable[byte(unit&0xff)])
+}
+
+func packedSwitchPayloadWidth(insns []uint16, i int) int {
+	if i+1 >= len(insns) {
+		return 1
+	}
+	size := int(insns[i+1])
+	return 4 + 2*size
+}
+
+func sparseSwitchPayloadWidth(insns []uint16, i int) int {
+	if i+1 >= len(insns) {
+		return 1
+	}
+	size := int(insns[i+1])
+	return 2 + 4*size
+}
+
+func fillArrayDataPayloadWidth(insns []uint16, i int) int {
+	if i+3 >= len(insns) {
+		return 1
+	}
+	elementWidth := int(insns[i+1])
+	size := int(insns[i+2]) | int(insns[i+3])<<16
+	dataUnits := (size*elementWidth + 1) / 2
+	return 4 + dataUnits
+}
+
+// widthTable maps an opcode byte to the fixed code-unit width of its
+// format, for every opcode whose width does not depend on its operands.
+// Ranges follow the Dalvik opcode listing grouped by format; unlisted
+// opcodes (reserved/unused in the dex version this package targets)
+// default to 1, the same as nop.
+var widthTable [256]uint8
+
+func init() {
+	for i := range widthTable {
+		widthTable[i] = 1
+	}
+
+	set := func(width uint8, ops ...byte) {
+		for _, op := range ops {
+			widthTable[op] = width
+		}
+	}
+	setRange := func(width uint8, lo, hi byte) {
+		for op := int(lo); op <= int(hi); op++ {
+			widthTable[op] = width
+		}
+	}
+
+	// move/from16, move-wide/from16, move-object/from16 (22x)
+	set(2, 0x02, 0x05, 0x08)
+	// move/16, move-wide/16, move-object/16 (32x)
+	set(3, 0x03, 0x06, 0x09)
+	// const/16 (21s), const/high16 (21h)
+	set(2, 0x13, 0x15)
+	// const (31i)
+	set(3, 0x14)
+	// const-wide/16 (21s), const-wide/high16 (21h)
+	set(2, 0x16, 0x19)
+	// const-wide/32 (31i)
+	set(3, 0x17)
+	// const-wide (51l)
+	set(5, 0x18)
+	// const-string, const-class, check-cast, new-instance (21c)
+	set(2, 0x1a, 0x1c, 0x1f, 0x22)
+	// const-string/jumbo (31c)
+	set(3, 0x1b)
+	// instance-of, new-array (22c)
+	set(2, 0x20, 0x23)
+	// filled-new-array (35c), filled-new-array/range (3rc)
+	set(3, 0x24, 0x25)
+	// fill-array-data, packed-switch, sparse-switch (31t)
+	set(3, 0x26, 0x2b, 0x2c)
+	// goto/16 (20t)
+	set(2, 0x29)
+	// goto/32 (30t)
+	set(3, 0x2a)
+	// cmpkind (23x): cmpl-float..cmp-long
+	setRange(2, 0x2d, 0x31)
+	// if-test (22t): if-eq..if-le
+	setRange(2, 0x32, 0x37)
+	// if-testz (21t): if-eqz..if-lez
+	setRange(2, 0x38, 0x3d)
+	// arrayop (23x): aget..aput-short
+	setRange(2, 0x44, 0x51)
+	// iinstanceop (22c): iget..iput-short
+	setRange(2, 0x52, 0x5f)
+	// sstaticop (21c): sget..sput-short
+	setRange(2, 0x60, 0x6d)
+	// invoke-kind (35c): invoke-virtual..invoke-interface
+	setRange(3, 0x6e, 0x72)
+	// invoke-kind/range (3rc)
+	setRange(3, 0x74, 0x78)
+	// binop (23x): add-int..rem-double
+	setRange(2, 0x90, 0xaf)
+	// binop/lit16 (22s)
+	setRange(2, 0xd0, 0xd7)
+	// binop/lit8 (22b)
+	setRange(2, 0xd8, 0xe2)
+	// invoke-polymorphic (45cc), invoke-polymorphic/range (4rcc)
+	set(4, 0xfa, 0xfb)
+	// invoke-custom (35c), invoke-custom/range (3rc)
+	set(3, 0xfc, 0xfd)
+	// const-method-handle, const-method-type (21c)
+	set(2, 0xfe, 0xff)
+
+	// Everything else that isn't already 1 (10x/11x/11n/12x formats -
+	// move, move-result*, return*, const/4, move-exception, monitor-*,
+	// array-length, throw, unop, binop/2addr - all occupy a single code
+	// unit) is left at the default set above.
+}
@@ -0,0 +1,218 @@
+package dex
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/avast/apkparser"
+)
+
+// PatternType identifies a recognized Java-level TLS-bypass idiom.
+type PatternType int
+
+const (
+	// TrustManagerBypass is an X509TrustManager whose checkServerTrusted or
+	// checkClientTrusted is empty (or just `return;`), so it trusts anything.
+	TrustManagerBypass PatternType = iota
+	// HostnameVerifierBypass is a HostnameVerifier.verify that always
+	// returns true, or code referencing ALLOW_ALL_HOSTNAME_VERIFIER.
+	HostnameVerifierBypass
+	// WebViewSSLBypass is a WebViewClient.onReceivedSslError override that
+	// calls SslErrorHandler.proceed, accepting the invalid certificate.
+	WebViewSSLBypass
+	// MissingCertificatePinner is OkHttp usage with no CertificatePinner
+	// reference anywhere in the app's bytecode.
+	MissingCertificatePinner
+)
+
+// Finding is one occurrence of a PatternType, located to a class/method
+// where applicable.
+type Finding struct {
+	Pattern PatternType
+	Class   string
+	Method  string
+	Detail  string
+}
+
+var classesDexName = regexp.MustCompile(`^classes\d*\.dex$`)
+
+// Scan iterates every classes*.dex entry of an APK's zip and reports
+// Java-level TLS-bypass patterns, aggregated across all DEX files since
+// Android splits a single app's code across them (multidex).
+func Scan(zip *apkparser.ZipReader) ([]Finding, error) {
+	var findings []Finding
+	usesOkHttp := false
+	hasCertificatePinner := false
+
+	var names []string
+	for name := range zip.File {
+		if classesDexName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		zipFile := zip.File[name]
+		if err := zipFile.Open(); err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, err)
+		}
+		data, err := io.ReadAll(zipFile)
+		zipFile.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+
+		f, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+
+		findings = append(findings, scanTrustManagers(f)...)
+		findings = append(findings, scanHostnameVerifiers(f)...)
+		findings = append(findings, scanWebViewSslErrors(f)...)
+
+		for _, t := range f.Strings {
+			if strings.Contains(t, "Lokhttp3/") {
+				usesOkHttp = true
+			}
+			if t == "Lokhttp3/CertificatePinner;" {
+				hasCertificatePinner = true
+			}
+		}
+	}
+
+	if usesOkHttp && !hasCertificatePinner {
+		findings = append(findings, Finding{
+			Pattern: MissingCertificatePinner,
+			Detail:  "app bundles OkHttp but never references okhttp3.CertificatePinner",
+		})
+	}
+
+	return findings, nil
+}
+
+// checkServerTrusted/checkClientTrusted bodies that do nothing but return
+// trust any certificate; both share the same (X509Certificate[], String)
+// shape so the method name alone is a reliable signal.
+func isTrustManagerMethod(name string) bool {
+	return name == "checkServerTrusted" || name == "checkClientTrusted"
+}
+
+// isTrivialBody reports whether a method's bytecode is empty, or consists
+// of nothing but a bare return/return-void (opcodes 0x0e-0x11).
+func isTrivialBody(insns []uint16) bool {
+	if len(insns) == 0 {
+		return true
+	}
+	if len(insns) != 1 {
+		return false
+	}
+	op := byte(insns[0] & 0xff)
+	return op >= 0x0e && op <= 0x11
+}
+
+func scanTrustManagers(f *File) []Finding {
+	var findings []Finding
+	for _, class := range f.Classes {
+		if !implementsInterface(class, "Ljavax/net/ssl/X509TrustManager;") {
+			continue
+		}
+		for _, m := range class.Methods {
+			if !isTrustManagerMethod(m.Name) {
+				continue
+			}
+			if isTrivialBody(m.Instructions) {
+				findings = append(findings, Finding{
+					Pattern: TrustManagerBypass,
+					Class:   class.Name,
+					Method:  m.Name,
+					Detail:  fmt.Sprintf("%s.%s has an empty body and accepts any certificate chain", class.Name, m.Name),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// const/4-then-return pattern for `return true;` as the only statement of
+// a boolean-returning method: the whole body is exactly two code units,
+// opcode 0x12 const/4 with literal 1, followed directly by a non-wide return.
+func returnsConstantTrue(insns []uint16) bool {
+	if len(insns) != 2 || byte(insns[0]&0xff) != 0x12 {
+		return false
+	}
+	// const/4 vA, #+B (format 11n): B is the signed 4-bit literal in
+	// the top nibble of the opcode unit.
+	nibble := int16(insns[0] >> 12)
+	if nibble > 7 {
+		nibble -= 16
+	}
+	nextOp := byte(insns[1] & 0xff)
+	return nibble == 1 && (nextOp == 0x0f || nextOp == 0x11)
+}
+
+func scanHostnameVerifiers(f *File) []Finding {
+	var findings []Finding
+	for _, class := range f.Classes {
+		if !implementsInterface(class, "Ljavax/net/ssl/HostnameVerifier;") {
+			continue
+		}
+		for _, m := range class.Methods {
+			if m.Name != "verify" {
+				continue
+			}
+			if returnsConstantTrue(m.Instructions) {
+				findings = append(findings, Finding{
+					Pattern: HostnameVerifierBypass,
+					Class:   class.Name,
+					Method:  m.Name,
+					Detail:  fmt.Sprintf("%s.verify unconditionally returns true", class.Name),
+				})
+			}
+		}
+	}
+	for _, s := range f.Strings {
+		if strings.Contains(s, "ALLOW_ALL_HOSTNAME_VERIFIER") {
+			findings = append(findings, Finding{
+				Pattern: HostnameVerifierBypass,
+				Detail:  "references javax.net.ssl.HttpsURLConnection.ALLOW_ALL_HOSTNAME_VERIFIER",
+			})
+		}
+	}
+	return findings
+}
+
+func scanWebViewSslErrors(f *File) []Finding {
+	var findings []Finding
+	for _, class := range f.Classes {
+		for _, m := range class.Methods {
+			if m.Name != "onReceivedSslError" {
+				continue
+			}
+			for _, inv := range m.Invokes {
+				if strings.Contains(inv.Class, "SslErrorHandler") && inv.Method == "proceed" {
+					findings = append(findings, Finding{
+						Pattern: WebViewSSLBypass,
+						Class:   class.Name,
+						Method:  m.Name,
+						Detail:  fmt.Sprintf("%s.onReceivedSslError calls SslErrorHandler.proceed, ignoring the TLS error", class.Name),
+					})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func implementsInterface(class Class, iface string) bool {
+	for _, i := range class.Interfaces {
+		if i == iface {
+			return true
+		}
+	}
+	return false
+}
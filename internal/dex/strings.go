@@ -0,0 +1,108 @@
+package dex
+
+import "fmt"
+
+// uleb128Reader reads successive ULEB128-encoded integers from data starting
+// at pos, advancing pos after each read.
+type uleb128Reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *uleb128Reader) read() (uint64, error) {
+	var result uint64
+	var shift uint
+	for {
+		if r.pos >= len(r.data) {
+			return 0, fmt.Errorf("uleb128: out of range")
+		}
+		b := r.data[r.pos]
+		r.pos++
+		result |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result, nil
+		}
+		shift += 7
+		if shift >= 64 {
+			return 0, fmt.Errorf("uleb128: too long")
+		}
+	}
+}
+
+// stringTable resolves string_id indices to decoded strings on demand,
+// without eagerly decoding every string_data_item up front.
+type stringTable struct {
+	data    []byte
+	offsets []uint32
+}
+
+func readStrings(data []byte, off, size uint32) (*stringTable, error) {
+	if int(off)+4*int(size) > len(data) {
+		return nil, fmt.Errorf("string_ids out of range")
+	}
+	offsets := make([]uint32, size)
+	for i := range offsets {
+		offsets[i] = le32(data, int(off)+4*i)
+	}
+	return &stringTable{data: data, offsets: offsets}, nil
+}
+
+// get decodes the MUTF-8 string_data_item referenced by string_id idx,
+// skipping its leading utf16_size ULEB128 prefix.
+func (t *stringTable) get(idx uint32) string {
+	if t == nil || int(idx) >= len(t.offsets) {
+		return ""
+	}
+	r := &uleb128Reader{data: t.data, pos: int(t.offsets[idx])}
+	utf16Size, err := r.read()
+	if err != nil {
+		return ""
+	}
+	return decodeMUTF8(t.data[r.pos:], int(utf16Size))
+}
+
+// decodeMUTF8 decodes a NUL-terminated Modified UTF-8 byte sequence into a
+// Go string, covering the differences from standard UTF-8 used by DEX
+// string_data_item (NUL encoded as 0xC0 0x80, and supplementary characters
+// encoded as CESU-8 surrogate pairs rather than 4-byte sequences).
+func decodeMUTF8(data []byte, utf16Size int) string {
+	out := make([]rune, 0, utf16Size)
+	i := 0
+	for i < len(data) {
+		b0 := data[i]
+		if b0 == 0 {
+			break
+		}
+		switch {
+		case b0&0x80 == 0:
+			out = append(out, rune(b0))
+			i++
+		case b0&0xe0 == 0xc0:
+			if i+1 >= len(data) {
+				i = len(data)
+				break
+			}
+			r := rune(b0&0x1f)<<6 | rune(data[i+1]&0x3f)
+			out = append(out, r)
+			i += 2
+		case b0&0xf0 == 0xe0:
+			if i+2 >= len(data) {
+				i = len(data)
+				break
+			}
+			hi := rune(b0&0x0f)<<12 | rune(data[i+1]&0x3f)<<6 | rune(data[i+2]&0x3f)
+			i += 3
+			// Surrogate pair: combine with the following encoded low surrogate.
+			if hi >= 0xd800 && hi <= 0xdbff && i+2 < len(data) && data[i] == 0xed {
+				lo := rune(data[i+1]&0x0f)<<6 | rune(data[i+2]&0x3f)
+				out = append(out, ((hi-0xd800)<<10|(lo&0x3ff))+0x10000)
+				i += 3
+			} else {
+				out = append(out, hi)
+			}
+		default:
+			i++
+		}
+	}
+	return string(out)
+}
@@ -1,6 +1,9 @@
 package manifest
 
-import "encoding/xml"
+import (
+	"bytes"
+	"encoding/xml"
+)
 
 type Manifest struct {
 	XMLName     xml.Name    `xml:"manifest"`
@@ -80,3 +83,26 @@ type Pin struct {
 	Digest  string   `xml:"digest,attr"`
 	Data    string   `xml:",innerxml"`
 }
+
+// DomainConfigLines returns the 1-indexed source line of each <domain-config>
+// start tag found in an NSC document's raw XML, in document order. Since
+// <domain-config> nests its children inline, that order is the same preorder
+// walk a flattened domain-config tree is built in, so callers can zip this
+// slice up against a flattened list by index to locate a specific risk.
+// A decode error truncates the result rather than failing outright, since
+// the caller already has the successfully-unmarshaled NetworkSecurityConfig
+// to work with.
+func DomainConfigLines(data []byte) []int {
+	var lines []int
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "domain-config" {
+			lines = append(lines, bytes.Count(data[:dec.InputOffset()], []byte("\n"))+1)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,87 @@
+// Package proxycas identifies well-known TLS-interception tools (mitmproxy,
+// Burp Suite, Charles, Fiddler, PacketCapture, HTTP Toolkit, ProxyMan) from
+// the CA certificate installed on a device, by CN pattern, so callers no
+// longer have to guess from a substring match on the certificate subject.
+//
+// There is deliberately no embedded table of SPKI fingerprints or serial
+// numbers: every one of these tools generates a fresh, self-signed CA
+// keypair per installation (the same reason mitmproxy can't be
+// fingerprinted this way either), so there is no fixed default root whose
+// hash or serial would ever match a real install. A table of such values
+// would either be fabricated or sourced from one specific install and
+// silently fail to match everyone else's, while looking like a verified
+// fingerprint match. Known, stable fingerprints can still be supplied per
+// deployment via --extra-proxy-cas.
+package proxycas
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// cnPatterns matches common subject CN conventions used by the default
+// certificates of these tools, since none of them has a fixed SPKI hash or
+// serial number to match on instead (see the package doc comment).
+var cnPatterns = []struct {
+	pattern *regexp.Regexp
+	tool    string
+}{
+	{regexp.MustCompile(`(?i)^mitmproxy`), "mitmproxy"},
+	{regexp.MustCompile(`(?i)portswigger|burp suite`), "Burp Suite"},
+	{regexp.MustCompile(`(?i)charles proxy`), "Charles Proxy"},
+	{regexp.MustCompile(`(?i)^do_not_trust_fiddlerroot|fiddler`), "Fiddler"},
+	{regexp.MustCompile(`(?i)packetcapture`), "PacketCapture"},
+	{regexp.MustCompile(`(?i)http toolkit`), "HTTP Toolkit"},
+	{regexp.MustCompile(`(?i)proxyman`), "ProxyMan"},
+}
+
+// Identify reports the interception tool whose default CA matches cert, by
+// SPKI hash (against extraHashes, as produced by LoadExtraHashes) first,
+// then CN pattern. The empty string is returned if no signal matches.
+func Identify(cert *x509.Certificate, extraHashes map[string]string) string {
+	spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	hash := hex.EncodeToString(spki[:])
+
+	if tool, ok := extraHashes[hash]; ok {
+		return tool
+	}
+
+	for _, p := range cnPatterns {
+		if p.pattern.MatchString(cert.Subject.CommonName) {
+			return p.tool
+		}
+	}
+
+	return ""
+}
+
+// LoadExtraHashes parses a newline-delimited list of hex-encoded SPKI
+// SHA-256 hashes (as produced by e.g. `openssl x509 -pubkey | openssl pkey
+// -pubin -outform der | sha256sum`), one per line, blank lines and
+// `#`-prefixed comments ignored. Each hash is reported as coming from
+// "user-provided" tool so callers can still flag and explain the match.
+func LoadExtraHashes(r io.Reader) (map[string]string, error) {
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		decoded, err := hex.DecodeString(line)
+		if err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid SPKI hash %q: must be a %d-byte hex-encoded SHA-256", line, sha256.Size)
+		}
+		hashes[strings.ToLower(line)] = "user-provided"
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
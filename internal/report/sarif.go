@@ -0,0 +1,146 @@
+// Package report converts scan results into third-party static-analysis
+// report formats, currently SARIF 2.1.0, so they can be uploaded to
+// GitHub code scanning, GitLab, or similar dashboards.
+//
+// Reference: https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+package report
+
+// Level is a SARIF result severity level.
+type Level string
+
+const (
+	LevelNote    Level = "note"
+	LevelWarning Level = "warning"
+	LevelError   Level = "error"
+)
+
+// Rule describes one reportingDescriptor under tool.driver.rules.
+type Rule struct {
+	ID               string
+	ShortDescription string
+}
+
+// Finding is one result: a rule violation located at an artifact, with an
+// optional path inside that artifact (e.g. a config file inside an APK
+// zip) for reviewers to jump to directly. InnerStartLine, if non-zero,
+// narrows that inner location down to a specific line (SARIF's region is
+// 1-indexed), e.g. the <domain-config> block a finding was raised against.
+type Finding struct {
+	RuleID         string
+	Level          Level
+	Message        string
+	Artifact       string
+	InnerPath      string
+	InnerStartLine int
+}
+
+const (
+	toolName           = "tlshunter"
+	toolInformationURI = "https://github.com/sigeryang/tlshunter"
+	schemaURI          = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+)
+
+type log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []run  `json:"runs"`
+}
+
+type run struct {
+	Tool    tool     `json:"tool"`
+	Results []result `json:"results"`
+}
+
+type tool struct {
+	Driver driver `json:"driver"`
+}
+
+type driver struct {
+	Name           string                `json:"name"`
+	InformationURI string                `json:"informationUri"`
+	Rules          []reportingDescriptor `json:"rules"`
+}
+
+type reportingDescriptor struct {
+	ID               string          `json:"id"`
+	ShortDescription multiformatText `json:"shortDescription"`
+}
+
+type multiformatText struct {
+	Text string `json:"text"`
+}
+
+type result struct {
+	RuleID    string          `json:"ruleId"`
+	Level     Level           `json:"level"`
+	Message   multiformatText `json:"message"`
+	Locations []location      `json:"locations"`
+}
+
+type location struct {
+	PhysicalLocation physicalLocation `json:"physicalLocation"`
+}
+
+type physicalLocation struct {
+	ArtifactLocation artifactLocation `json:"artifactLocation"`
+	Region           *region          `json:"region,omitempty"`
+}
+
+type artifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type region struct {
+	StartLine int `json:"startLine"`
+}
+
+// Build assembles a SARIF 2.1.0 log from the given rule catalog and
+// findings. Rules are emitted in the order given; findings are emitted in
+// the order given, one result per finding.
+func Build(rules []Rule, findings []Finding) interface{} {
+	driverRules := make([]reportingDescriptor, 0, len(rules))
+	for _, r := range rules {
+		driverRules = append(driverRules, reportingDescriptor{
+			ID:               r.ID,
+			ShortDescription: multiformatText{Text: r.ShortDescription},
+		})
+	}
+
+	results := make([]result, 0, len(findings))
+	for _, f := range findings {
+		locations := []location{{
+			PhysicalLocation: physicalLocation{
+				ArtifactLocation: artifactLocation{URI: f.Artifact},
+			},
+		}}
+		if f.InnerPath != "" {
+			inner := physicalLocation{ArtifactLocation: artifactLocation{URI: f.InnerPath}}
+			if f.InnerStartLine > 0 {
+				inner.Region = &region{StartLine: f.InnerStartLine}
+			}
+			locations = append(locations, location{PhysicalLocation: inner})
+		}
+
+		results = append(results, result{
+			RuleID:    f.RuleID,
+			Level:     f.Level,
+			Message:   multiformatText{Text: f.Message},
+			Locations: locations,
+		})
+	}
+
+	return log{
+		Schema:  schemaURI,
+		Version: "2.1.0",
+		Runs: []run{
+			{
+				Tool: tool{Driver: driver{
+					Name:           toolName,
+					InformationURI: toolInformationURI,
+					Rules:          driverRules,
+				}},
+				Results: results,
+			},
+		},
+	}
+}
@@ -0,0 +1,118 @@
+// Package scanner runs per-file work across a bounded pool of goroutines,
+// streaming each result back to the caller as it completes so batches of
+// thousands of APKs report incremental progress instead of going silent
+// until the whole run finishes.
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// Stage names the phase of APK processing a ScanError occurred in.
+type Stage string
+
+const (
+	StageOpenZip        Stage = "open-zip"
+	StageParseManifest  Stage = "parse-manifest"
+	StageParseResources Stage = "parse-resources"
+	StageParseNSC       Stage = "parse-nsc"
+	StageAnalyze        Stage = "analyze"
+)
+
+// ScanError wraps a failure encountered while processing a single file,
+// recording which stage it happened at alongside the file path.
+type ScanError struct {
+	File  string
+	Stage Stage
+	Err   error
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf(`%s: %s: %v`, e.File, e.Stage, e.Err)
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// NewError wraps err with file/stage context, or returns nil if err is nil
+// so callers can write `return result, scanner.NewError(file, stage, err)`
+// unconditionally.
+func NewError(file string, stage Stage, err error) *ScanError {
+	if err == nil {
+		return nil
+	}
+	return &ScanError{File: file, Stage: stage, Err: err}
+}
+
+// Func does the actual per-file work submitted to Run.
+type Func[T any] func(ctx context.Context, file string) (T, *ScanError)
+
+// OnResult is called once per file as its result becomes available, in
+// completion order (not necessarily input order). Run calls it from a
+// single goroutine, so implementations don't need their own locking.
+type OnResult[T any] func(file string, value T, err *ScanError)
+
+// Run processes files concurrently across up to concurrency goroutines
+// (runtime.NumCPU() if concurrency <= 0), calling fn for each and
+// reporting every outcome to onResult as soon as it's ready. It blocks
+// until every file has been processed or ctx is canceled, then returns the
+// successful values and the errors, both in completion order.
+func Run[T any](ctx context.Context, files []string, concurrency int, fn Func[T], onResult OnResult[T]) ([]T, []*ScanError) {
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	type outcome struct {
+		file  string
+		value T
+		err   *ScanError
+	}
+
+	jobs := make(chan string)
+	out := make(chan outcome)
+
+	var workers sync.WaitGroup
+	workers.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer workers.Done()
+			for file := range jobs {
+				value, err := fn(ctx, file)
+				out <- outcome{file: file, value: value, err: err}
+			}
+		}()
+	}
+
+	go func() {
+	feed:
+		for _, file := range files {
+			select {
+			case <-ctx.Done():
+				break feed
+			case jobs <- file:
+			}
+		}
+		close(jobs)
+		workers.Wait()
+		close(out)
+	}()
+
+	var values []T
+	var errs []*ScanError
+	for res := range out {
+		if res.err != nil {
+			errs = append(errs, res.err)
+		} else {
+			values = append(values, res.value)
+		}
+		if onResult != nil {
+			onResult(res.file, res.value, res.err)
+		}
+	}
+
+	return values, errs
+}